@@ -0,0 +1,106 @@
+// Package cassette records the requests made to an RTWire compatible server,
+// such as the live sandbox, and their responses as a cassette. A cassette can
+// be replayed to serve the recorded responses again or compared with the
+// responses of the mock, for example:
+//
+//	rec, err := cassette.NewRecorder(http.DefaultClient,
+//	    "https://api.rtwire.com")
+//	...
+//	http.ListenAndServe(":8085", rec)
+//	...
+//	err = rec.Cassette().Write(file)
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Cassette is a sequence of recorded interactions, in the order they were
+// made. It is written as JSON so that recordings can be kept next to tests
+// and reviewed.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Credentials are not recorded.
+type Request struct {
+	Method string `json:"method"`
+
+	// URI is the path and query of the request, for example
+	// /v1/mainnet/accounts/?limit=10.
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Body is the body of a request or response. JSON bodies are recorded as
+// JSON and other bodies as a JSON string.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if len(b) == 0 {
+		return []byte("null"), nil
+	}
+	if json.Valid(b) {
+		return []byte(compact(b)), nil
+	}
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A recorded JSON string is read
+// as the text it holds, as non-JSON bodies are recorded as strings.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	switch {
+	case string(data) == "null":
+		*b = nil
+	case json.Unmarshal(data, &text) == nil:
+		*b = Body(text)
+	default:
+		*b = Body(compact(data))
+	}
+	return nil
+}
+
+// ReadCassette reads a cassette written by Write from r.
+func ReadCassette(r io.Reader) (*Cassette, error) {
+	c := &Cassette{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, fmt.Errorf("invalid cassette: %v", err)
+	}
+	for i, in := range c.Interactions {
+		if in.Request.Method == "" || in.Request.URI == "" {
+			return nil, fmt.Errorf("invalid cassette: interactions[%d]: "+
+				"method and uri must be set", i)
+		}
+		if in.Response.Status < 100 || in.Response.Status > 599 {
+			return nil, fmt.Errorf("invalid cassette: interactions[%d]: "+
+				"invalid status %d", i, in.Response.Status)
+		}
+	}
+	return c, nil
+}
+
+// Write writes c to w as indented JSON.
+func (c *Cassette) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
@@ -0,0 +1,189 @@
+package cassette_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rtwire/mock/cassette"
+	"github.com/rtwire/mock/client"
+	"github.com/rtwire/mock/service"
+)
+
+// session makes requests covering successes and errors and returns the IDs
+// of the accounts it created.
+func session(t *testing.T, cl *client.Client) (int64, int64) {
+	from, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.CreateAddress(to.ID); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := cl.CreateTransactionIDs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.Transfer(ids[0], from.ID, to.ID, 100)
+	if client.ErrorCode(err) != client.InsufficientFunds {
+		t.Fatalf("expected insufficient funds got %v", err)
+	}
+	if _, err := cl.Account(to.ID); err != nil {
+		t.Fatal(err)
+	}
+	return from.ID, to.ID
+}
+
+func record(t *testing.T) *cassette.Cassette {
+	upstream := httptest.NewServer(service.New())
+	defer upstream.Close()
+
+	rec, err := cassette.NewRecorder(http.DefaultClient, upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	session(t, client.New(http.DefaultClient, proxy.URL+"/v1/mainnet",
+		"user", "pass"))
+	return rec.Cassette()
+}
+
+func TestRecordGzip(t *testing.T) {
+	const body = `{"payload":[]}`
+	upstream := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				w.Write([]byte(body))
+				return
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(body))
+			gz.Close()
+		}))
+	defer upstream.Close()
+
+	rec, err := cassette.NewRecorder(http.DefaultClient, upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	req, err := http.NewRequest("GET", proxy.URL+"/v1/mainnet/accounts/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body || resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("expected %s got %q encoded %q", body, got,
+			resp.Header.Get("Content-Encoding"))
+	}
+
+	// The response is recorded decompressed.
+	c := rec.Cassette()
+	if len(c.Interactions) != 1 {
+		t.Fatalf("expected 1 interaction got %d", len(c.Interactions))
+	}
+	res := c.Interactions[0].Response
+	if string(res.Body) != body || res.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected response %+v", res)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	c := record(t)
+	if len(c.Interactions) != 6 {
+		t.Fatalf("expected 6 interactions got %d", len(c.Interactions))
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Authorization") {
+		t.Fatalf("credentials recorded %s", buf.String())
+	}
+	written := buf.String()
+	read, err := cassette.ReadCassette(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := read.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != written {
+		t.Fatalf("expected cassette\n%s\ngot\n%s", written, buf.String())
+	}
+
+	// Replaying needs no credentials and returns the recorded accounts.
+	p := cassette.NewPlayer(read)
+	s := httptest.NewServer(p)
+	defer s.Close()
+	cl := client.New(http.DefaultClient, s.URL+"/v1/mainnet", "", "")
+
+	from, to := session(t, cl)
+	if len(p.Unplayed()) != 0 {
+		t.Fatalf("unexpected unplayed interactions %+v", p.Unplayed())
+	}
+	if acc, err := cl.Account(to); err != nil || acc.ID != to {
+		t.Fatalf("unexpected account %+v %v", acc, err)
+	}
+	_, err = cl.Account(from)
+	if client.ErrorCode(err) != "interaction_not_found" {
+		t.Fatalf("expected interaction not found got %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c := record(t)
+	auth := func(r *http.Request) { r.SetBasicAuth("user", "pass") }
+
+	// A fresh mock creates different IDs, which are used in later requests.
+	if diffs := cassette.Compare(c, service.New(), auth); len(diffs) != 0 {
+		t.Fatalf("unexpected differences %v", diffs)
+	}
+
+	c.Interactions[1].Response.Status = http.StatusOK
+	c.Interactions[4].Response.Body = bytes.Replace(
+		c.Interactions[4].Response.Body, []byte("insufficient_funds"),
+		[]byte("account_frozen"), 1)
+	c.Interactions[5].Response.Body = bytes.Replace(
+		c.Interactions[5].Response.Body, []byte(`"balance"`),
+		[]byte(`"available"`), 1)
+
+	expected := []string{
+		"POST /v1/mainnet/accounts/: status 200 recorded, 201 from mock",
+		`PUT /v1/mainnet/transactions/: body.payload[0].code: ` +
+			`"account_frozen" recorded, "insufficient_funds" from mock`,
+		"GET " + c.Interactions[5].Request.URI +
+			": body.payload[0].available: missing from mock",
+		"GET " + c.Interactions[5].Request.URI +
+			": body.payload[0].balance: not recorded",
+	}
+	if diffs := cassette.Compare(c, service.New(), auth); !reflect.DeepEqual(
+		diffs, expected) {
+		t.Fatalf("expected differences\n%s\ngot\n%s",
+			strings.Join(expected, "\n"), strings.Join(diffs, "\n"))
+	}
+}
@@ -0,0 +1,276 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+)
+
+// Compare makes the requests of c, in order, to h, such as the mock service,
+// and returns each way its responses differ from those recorded. auth, if not
+// nil, is called to add credentials to each request.
+//
+// Responses are compared by status and by the shape of their JSON bodies:
+// the fields present and the types of their values. Values are compared only
+// for the type and code fields, such as error codes. IDs and addresses
+// returned by h are used in place of the recorded ones in later requests,
+// so a cassette recorded against the sandbox can be made against the mock.
+func Compare(c *Cassette, h http.Handler,
+	auth func(r *http.Request)) []string {
+
+	cmp := comparer{
+		handler: h,
+		auth:    auth,
+		ids:     map[string]string{},
+	}
+	for _, in := range c.Interactions {
+		cmp.compare(in)
+	}
+	return cmp.diffs
+}
+
+type comparer struct {
+	handler http.Handler
+	auth    func(r *http.Request)
+
+	// ids maps recorded IDs and addresses to those returned by the handler.
+	ids   map[string]string
+	diffs []string
+}
+
+func (cmp *comparer) compare(in Interaction) {
+	r := httptest.NewRequest(in.Request.Method, cmp.rewriteURI(in.Request.URI),
+		bytes.NewReader(cmp.rewriteBody(in.Request.Body)))
+	for name, values := range in.Request.Header {
+		r.Header[name] = values
+	}
+	if cmp.auth != nil {
+		cmp.auth(r)
+	}
+
+	w := httptest.NewRecorder()
+	cmp.handler.ServeHTTP(w, r)
+
+	recorded, recordedOK := decode(in.Response.Body)
+	actual, actualOK := decode(w.Body.Bytes())
+	if recordedOK && actualOK {
+		cmp.learn(recorded, actual)
+	}
+
+	prefix := in.Request.Method + " " + in.Request.URI
+	switch {
+	case w.Code != in.Response.Status:
+		cmp.diffs = append(cmp.diffs, fmt.Sprintf(
+			"%s: status %d recorded, %d from mock", prefix,
+			in.Response.Status, w.Code))
+		return
+	case !recordedOK && !actualOK:
+		return
+	case recordedOK != actualOK:
+		cmp.diffs = append(cmp.diffs, fmt.Sprintf(
+			"%s: body is %s recorded, %s from mock", prefix,
+			bodyKind(recordedOK), bodyKind(actualOK)))
+		return
+	}
+
+	for _, d := range diff("body", recorded, actual) {
+		cmp.diffs = append(cmp.diffs, prefix+": "+d)
+	}
+}
+
+func bodyKind(isJSON bool) string {
+	if isJSON {
+		return "JSON"
+	}
+	return "not JSON"
+}
+
+func decode(b []byte) (interface{}, bool) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// idField reports whether values of the field name identify an account,
+// transaction or address.
+func idField(name string) bool {
+	return name == "id" || strings.HasSuffix(name, "ID") ||
+		name == "address" || strings.HasSuffix(name, "Address")
+}
+
+// learn maps the IDs and addresses of recorded to those at the same place in
+// actual.
+func (cmp *comparer) learn(recorded, actual interface{}) {
+	switch r := recorded.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name, rv := range r {
+			av, exists := a[name]
+			if !exists {
+				continue
+			}
+			if rs, as := scalar(rv), scalar(av); idField(name) &&
+				rs != "" && as != "" {
+				cmp.ids[rs] = as
+				continue
+			}
+			cmp.learn(rv, av)
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < len(r) && i < len(a); i++ {
+			cmp.learn(r[i], a[i])
+		}
+	}
+}
+
+// scalar returns the text of a number or string and "" for other values.
+func scalar(v interface{}) string {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	}
+	return ""
+}
+
+// rewriteURI replaces recorded IDs and addresses in the segments of uri.
+func (cmp *comparer) rewriteURI(uri string) string {
+	path, query := uri, ""
+	if i := strings.Index(uri, "?"); i >= 0 {
+		path, query = uri[:i], uri[i:]
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if id, exists := cmp.ids[s]; exists {
+			segments[i] = id
+		}
+	}
+	return strings.Join(segments, "/") + query
+}
+
+// rewriteBody replaces recorded IDs and addresses in the fields of body.
+func (cmp *comparer) rewriteBody(body []byte) []byte {
+	v, ok := decode(body)
+	if !ok || len(cmp.ids) == 0 {
+		return body
+	}
+	b, err := json.Marshal(cmp.rewrite(v))
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+func (cmp *comparer) rewrite(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, fv := range v {
+			id, exists := cmp.ids[scalar(fv)]
+			switch {
+			case !idField(name) || !exists:
+				v[name] = cmp.rewrite(fv)
+			case isNumber(fv):
+				v[name] = json.Number(id)
+			default:
+				v[name] = id
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = cmp.rewrite(v[i])
+		}
+	}
+	return v
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := v.(json.Number)
+	return ok
+}
+
+// diff returns the differences in shape between recorded and actual at path.
+func diff(path string, recorded, actual interface{}) []string {
+	if rt, at := typeName(recorded), typeName(actual); rt != at {
+		return []string{fmt.Sprintf("%s: %s recorded, %s from mock", path,
+			rt, at)}
+	}
+
+	diffs := []string{}
+	switch r := recorded.(type) {
+	case map[string]interface{}:
+		a := actual.(map[string]interface{})
+		for _, name := range keys(r, a) {
+			rv, inRecorded := r[name]
+			av, inActual := a[name]
+			switch {
+			case !inActual:
+				diffs = append(diffs, path+"."+name+": missing from mock")
+			case !inRecorded:
+				diffs = append(diffs, path+"."+name+": not recorded")
+			case (name == "type" || name == "code") &&
+				scalar(rv) != scalar(av):
+				diffs = append(diffs, fmt.Sprintf("%s.%s: %q recorded, "+
+					"%q from mock", path, name, scalar(rv), scalar(av)))
+			default:
+				diffs = append(diffs, diff(path+"."+name, rv, av)...)
+			}
+		}
+	case []interface{}:
+		a := actual.([]interface{})
+		for i := 0; i < len(r) && i < len(a); i++ {
+			diffs = append(diffs, diff(fmt.Sprintf("%s[%d]", path, i), r[i],
+				a[i])...)
+		}
+	}
+	return diffs
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// keys returns the field names of a and b in lexical order.
+func keys(a, b map[string]interface{}) []string {
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, exists := a[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,153 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// skippedHeaders are not forwarded or not recorded. They either hold
+// credentials, apply to a single connection or change with every response.
+// Accept-Encoding is left to the HTTP client, which then decompresses the
+// response before it is recorded.
+var skippedHeaders = map[string]bool{
+	"Authorization":       true,
+	"X-Api-Key":           true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Accept-Encoding":     true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Content-Length":      true,
+	"Date":                true,
+}
+
+// credentialHeaders are forwarded to the upstream server but not recorded.
+var credentialHeaders = map[string]bool{
+	"Authorization": true,
+	"X-Api-Key":     true,
+}
+
+// Recorder is an http.Handler that forwards every request to an upstream
+// server and records it, with the response, in a cassette.
+type Recorder struct {
+	httpClient *http.Client
+	upstream   *url.URL
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a recorder forwarding requests to the server at
+// upstream, such as https://api.rtwire.com or http://localhost:8086. The path
+// of each request is appended to the path of upstream.
+func NewRecorder(httpClient *http.Client, upstream string) (*Recorder, error) {
+	u, err := url.Parse(upstream)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream url %q", upstream)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return &Recorder{
+		httpClient: httpClient,
+		upstream:   u,
+	}, nil
+}
+
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u := *rec.upstream
+	u.Path += r.URL.Path
+	u.RawPath = ""
+	u.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for name, values := range r.Header {
+		if credentialHeaders[name] || !skippedHeaders[name] {
+			req.Header[name] = values
+		}
+	}
+
+	resp, err := rec.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Error forwarding %s %s: %v.", r.Method, r.URL, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Error reading response to %s %s: %v.", r.Method, r.URL,
+			err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rec.mu.Lock()
+	rec.cassette.Interactions = append(rec.cassette.Interactions, Interaction{
+		Request: Request{
+			Method: r.Method,
+			URI:    r.URL.RequestURI(),
+			Header: recordedHeader(r.Header),
+			Body:   body,
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: recordedHeader(resp.Header),
+			Body:   respBody,
+		},
+	})
+	rec.mu.Unlock()
+
+	for name, values := range resp.Header {
+		if !skippedHeaders[name] {
+			w.Header()[name] = values
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+// Cassette returns the interactions recorded so far.
+func (rec *Recorder) Cassette() *Cassette {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	interactions := make([]Interaction, len(rec.cassette.Interactions))
+	copy(interactions, rec.cassette.Interactions)
+	return &Cassette{Interactions: interactions}
+}
+
+func recordedHeader(h http.Header) http.Header {
+	recorded := http.Header{}
+	for name, values := range h {
+		if !skippedHeaders[name] {
+			recorded[name] = values
+		}
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+	return recorded
+}
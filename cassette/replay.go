@@ -0,0 +1,127 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Player is an http.Handler that serves the responses of a cassette.
+//
+// A request is answered by the first unplayed interaction with the same
+// method, URI and body, with JSON bodies compared regardless of formatting.
+// Once every such interaction has been played the last of them is played
+// again, so a client polling an endpoint receives its final recorded state.
+// Requests that match no interaction receive a 404 Not Found error with the
+// code interaction_not_found.
+type Player struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	played       []bool
+}
+
+// NewPlayer returns a player serving the interactions of c.
+func NewPlayer(c *Cassette) *Player {
+	return &Player{
+		interactions: c.Interactions,
+		played:       make([]bool, len(c.Interactions)),
+	}
+}
+
+func (p *Player) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in, found := p.play(r.Method, r.URL.RequestURI(), body)
+	if !found {
+		sendError(w, http.StatusNotFound, "interaction_not_found",
+			fmt.Sprintf("no interaction recorded for %s %s", r.Method,
+				r.URL.RequestURI()))
+		return
+	}
+
+	for name, values := range in.Response.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(in.Response.Status)
+	w.Write(in.Response.Body)
+}
+
+// play returns the interaction answering a request and marks it played.
+func (p *Player) play(method, uri string, body []byte) (Interaction, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last := -1
+	for i, in := range p.interactions {
+		if in.Request.Method != method || in.Request.URI != uri ||
+			!sameBody(in.Request.Body, body) {
+			continue
+		}
+		if !p.played[i] {
+			p.played[i] = true
+			return in, true
+		}
+		last = i
+	}
+	if last < 0 {
+		return Interaction{}, false
+	}
+	return p.interactions[last], true
+}
+
+// Unplayed returns the interactions that have not been played, for example
+// to check that a test made every recorded request.
+func (p *Player) Unplayed() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unplayed := []Interaction{}
+	for i, in := range p.interactions {
+		if !p.played[i] {
+			unplayed = append(unplayed, in)
+		}
+	}
+	return unplayed
+}
+
+func sameBody(a, b []byte) bool {
+	if json.Valid(a) && json.Valid(b) {
+		return compact(a) == compact(b)
+	}
+	return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+}
+
+func compact(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// sendError sends an error in the format of the RTWire API.
+func sendError(w http.ResponseWriter, status int, code, msg string) {
+	resp := struct {
+		Type    string        `json:"type"`
+		Payload []interface{} `json:"payload"`
+	}{
+		Type: "errors",
+		Payload: []interface{}{struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}{code, msg}},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Account is an account holding funds in satoshis.
+type Account struct {
+	ID      int64 `json:"id"`
+	Balance int64 `json:"balance"`
+
+	// Frozen accounts have a negative balance after a credit was reversed
+	// and can not send funds.
+	Frozen bool `json:"frozen"`
+}
+
+// CreateAccount creates an account with a zero balance.
+func (cl *Client) CreateAccount() (Account, error) {
+	accs := []Account{}
+	if err := cl.do("POST", "/accounts/", nil, &accs); err != nil {
+		return Account{}, err
+	}
+	if len(accs) == 0 {
+		return Account{}, ErrEmptyPayload
+	}
+	return accs[0], nil
+}
+
+// Account returns the account id.
+func (cl *Client) Account(id int64) (Account, error) {
+	accs := []Account{}
+	path := fmt.Sprintf("/accounts/%d", id)
+	if err := cl.do("GET", path, nil, &accs); err != nil {
+		return Account{}, err
+	}
+	if len(accs) == 0 {
+		return Account{}, ErrEmptyPayload
+	}
+	return accs[0], nil
+}
+
+// AccountByLabel returns a labelled account such as "_fee", which receives
+// the fees of debits.
+func (cl *Client) AccountByLabel(label string) (Account, error) {
+	accs := []Account{}
+	path := "/accounts/labels/" + url.PathEscape(label) + "/"
+	if err := cl.do("GET", path, nil, &accs); err != nil {
+		return Account{}, err
+	}
+	if len(accs) == 0 {
+		return Account{}, ErrEmptyPayload
+	}
+	return accs[0], nil
+}
+
+// CreateAddress returns a new bitcoin address that credits the account.
+func (cl *Client) CreateAddress(accountID int64) (string, error) {
+	addrs := []struct {
+		Address string `json:"address"`
+	}{}
+	path := fmt.Sprintf("/accounts/%d/addresses/", accountID)
+	if err := cl.do("POST", path, nil, &addrs); err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", ErrEmptyPayload
+	}
+	return addrs[0].Address, nil
+}
+
+// pageSize is the number of items requested by iterators, which is the most
+// the service returns at once.
+const pageSize = 50
+
+// pager requests the pages of items of a list endpoint, following the next
+// value of each response.
+type pager struct {
+	cl   *Client
+	path string
+	next string
+	done bool
+	err  error
+}
+
+// fetch decodes the next page of items into items and returns false if there
+// are no more or an error occurred. count returns the number decoded.
+func (p *pager) fetch(items interface{}, count func() int) bool {
+	if p.done {
+		return false
+	}
+
+	path := fmt.Sprintf("%s?limit=%d", p.path, pageSize)
+	if p.next != "" {
+		path += "&next=" + url.QueryEscape(p.next)
+	}
+	next, err := p.cl.send("GET", path, nil, items)
+	if err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+
+	p.next = next
+	p.done = next == ""
+	return count() > 0
+}
+
+// AccountIterator iterates over accounts, requesting them a page at a time.
+// For example:
+//
+//	it := cl.Accounts()
+//	for it.Next() {
+//	    acc := it.Account()
+//	}
+//	if err := it.Err(); err != nil {
+//	    ...
+//	}
+type AccountIterator struct {
+	pager
+	page []Account
+	acc  Account
+}
+
+// Accounts returns an iterator over every account.
+func (cl *Client) Accounts() *AccountIterator {
+	return &AccountIterator{pager: pager{cl: cl, path: "/accounts/"}}
+}
+
+// Next moves to the next account, returning false when there are none left
+// or an error occurred.
+func (it *AccountIterator) Next() bool {
+	if len(it.page) == 0 {
+		it.page = []Account{}
+		if !it.fetch(&it.page, func() int { return len(it.page) }) {
+			return false
+		}
+	}
+	it.acc, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Account returns the current account.
+func (it *AccountIterator) Account() Account {
+	return it.acc
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *AccountIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,189 @@
+// Package client implements a client for the RTWire API. It can be used
+// against the live service or the mock service in this repository, for
+// example:
+//
+//	cl := client.New(http.DefaultClient, "http://localhost:8085/v1/mainnet",
+//	    "user", "pass")
+//	acc, err := cl.CreateAccount()
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is returned for requests that the service responds to with an error.
+type Error struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// Code identifies the error, for example InsufficientFunds. It is empty
+	// if the response did not have an error body.
+	Code string
+
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("rtwire: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("rtwire: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Error codes that clients commonly handle. Every code is listed in the
+// README of the mock service.
+const (
+	Unauthenticated     = "unauthenticated"
+	RateLimited         = "rate_limited"
+	AccountNotFound     = "account_not_found"
+	AccountFrozen       = "account_frozen"
+	InsufficientFunds   = "insufficient_funds"
+	InvalidTxID         = "invalid_tx_id"
+	TxIDExpired         = "tx_id_expired"
+	TxIDConflict        = "tx_id_conflict"
+	TxIDUsed            = "tx_id_used"
+	TxIDCancelled       = "tx_id_cancelled"
+	TransactionNotFound = "transaction_not_found"
+	HookExists          = "hook_exists"
+)
+
+// ErrEmptyPayload is returned when a successful response that should hold an
+// item, such as a created account, holds none.
+var ErrEmptyPayload = errors.New("rtwire: empty payload")
+
+// ErrorCode returns the code of err if it is an *Error and "" otherwise.
+func ErrorCode(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return ""
+}
+
+// Client sends requests to the endpoints of one network, such as
+// https://api.rtwire.com/v1/mainnet.
+type Client struct {
+	httpClient *http.Client
+	url        string
+
+	user  string
+	pass  string
+	token string
+}
+
+// New returns a client for the network at url that authenticates with user
+// and pass.
+func New(httpClient *http.Client, url, user, pass string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		url:        strings.TrimSuffix(url, "/"),
+		user:       user,
+		pass:       pass,
+	}
+}
+
+// NewWithToken returns a client for the network at url that authenticates
+// with an API token.
+func NewWithToken(httpClient *http.Client, url, token string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		url:        strings.TrimSuffix(url, "/"),
+		token:      token,
+	}
+}
+
+type jsonMessage struct {
+	Type    string          `json:"type"`
+	Next    string          `json:"next,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type errorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// do sends a request with body, if not nil, JSON encoded and decodes the
+// payload of the response into payload, if not nil.
+func (cl *Client) do(method, path string, body, payload interface{}) error {
+	_, err := cl.send(method, path, body, payload)
+	return err
+}
+
+// send is like do but also returns the next value of the response, which
+// selects the following page of a list and is empty after the last page.
+func (cl *Client) send(method, path string, body,
+	payload interface{}) (string, error) {
+	var reqBody io.Reader
+	if body != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return "", err
+		}
+		reqBody = &buf
+	}
+
+	req, err := http.NewRequest(method, cl.url+path, reqBody)
+	if err != nil {
+		return "", err
+	}
+	if cl.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.token)
+	} else {
+		req.SetBasicAuth(cl.user, cl.pass)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := cl.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", responseError(resp)
+	}
+	if payload == nil {
+		return "", nil
+	}
+
+	msg := jsonMessage{}
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(msg.Payload, payload); err != nil {
+		return "", err
+	}
+	return msg.Next, nil
+}
+
+// responseError returns the first error in the body of resp.
+func responseError(resp *http.Response) error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+	}
+
+	msg := jsonMessage{}
+	errs := []errorPayload{}
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil ||
+		msg.Type != "errors" {
+		return e
+	}
+	if err := json.Unmarshal(msg.Payload, &errs); err != nil ||
+		len(errs) == 0 {
+		return e
+	}
+
+	e.Code = errs[0].Code
+	e.Message = errs[0].Message
+	return e
+}
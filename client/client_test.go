@@ -0,0 +1,359 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtwire/mock/client"
+	"github.com/rtwire/mock/service"
+)
+
+// newClient starts a mock service, which must be closed, and returns a client
+// of its main network.
+func newClient() (*client.Client, *httptest.Server) {
+	s := httptest.NewServer(service.New())
+	return client.New(http.DefaultClient, s.URL+"/v1/mainnet", "user",
+		"pass"), s
+}
+
+// credit credits addr with value using the mock endpoint.
+func credit(t *testing.T, s *httptest.Server, addr string, value int64) {
+	body, err := json.Marshal(map[string]int64{"value": value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("POST",
+		s.URL+"/_mock/mainnet/addresses/"+addr, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth("admin", "pass")
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func fundedAccount(t *testing.T, cl *client.Client, s *httptest.Server,
+	value int64) client.Account {
+
+	acc, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := cl.CreateAddress(acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credit(t, s, addr, value)
+	return acc
+}
+
+func txID(t *testing.T, cl *client.Client) int64 {
+	ids, err := cl.CreateTransactionIDs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids[0]
+}
+
+func TestTransfer(t *testing.T) {
+	cl, s := newClient()
+	defer s.Close()
+
+	from := fundedAccount(t, cl, s, 1000)
+	to, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := txID(t, cl)
+	if err := cl.Transfer(id, from.ID, to.ID, 400); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := cl.Transaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type != client.Transfer || tx.Value != 400 ||
+		tx.ToAccountID != to.ID {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	acc, err := cl.Account(from.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Balance != 600 {
+		t.Fatalf("expected balance 600 got %d", acc.Balance)
+	}
+
+	err = cl.Transfer(txID(t, cl), from.ID, to.ID, 1000)
+	e, ok := err.(*client.Error)
+	if !ok || e.StatusCode != http.StatusBadRequest ||
+		e.Code != client.InsufficientFunds {
+		t.Fatalf("expected insufficient funds got %v", err)
+	}
+}
+
+func TestDebit(t *testing.T) {
+	cl, s := newClient()
+	defer s.Close()
+
+	from := fundedAccount(t, cl, s, 100000)
+	to, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := cl.CreateAddress(to.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := txID(t, cl)
+	if err := cl.Debit(id, from.ID, addr, 5000); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := cl.Transaction(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type != client.Debit || len(tx.Outputs) != 1 ||
+		tx.Outputs[0].ToAddress != addr || tx.Fee == 0 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	// The network fee is paid from the fee account.
+	fee, err := cl.AccountByLabel("_fee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.Balance != -tx.Fee {
+		t.Fatalf("expected fee balance %d got %d", -tx.Fee, fee.Balance)
+	}
+
+	if err := cl.CancelTransactionID(id); client.ErrorCode(err) !=
+		client.TxIDUsed {
+		t.Fatalf("expected tx id used got %v", err)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	cl, s := newClient()
+	defer s.Close()
+
+	from := fundedAccount(t, cl, s, 1000)
+	to, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More transactions than fit in a page.
+	const n = 60
+	for i := 0; i < n; i++ {
+		if err := cl.Transfer(txID(t, cl), from.ID, to.ID, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	it := cl.Transactions(from.ID)
+	count := 0
+	for it.Next() {
+		tx := it.Transaction()
+		if count == 0 && tx.Type != client.Credit {
+			t.Fatalf("expected credit got %+v", tx)
+		}
+		if count > 0 && (tx.Type != client.Transfer || tx.Value != 1) {
+			t.Fatalf("unexpected transaction %d %+v", count, tx)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != n+1 {
+		t.Fatalf("expected %d transactions got %d", n+1, count)
+	}
+
+	it = cl.Transactions(999)
+	if it.Next() {
+		t.Fatal("expected no transactions")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	cl, s := newClient()
+	defer s.Close()
+
+	ids := map[int64]bool{}
+	for i := 0; i < 60; i++ {
+		acc, err := cl.CreateAccount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[acc.ID] = true
+	}
+
+	it := cl.Accounts()
+	for it.Next() {
+		delete(ids, it.Account().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("%d accounts not iterated", len(ids))
+	}
+}
+
+func TestAccountsNext(t *testing.T) {
+	// The server chooses the next value of each page.
+	pages := map[string]string{
+		"":  `{"type":"accounts","next":"b","payload":[{"id":1}]}`,
+		"b": `{"type":"accounts","next":"c","payload":[{"id":2}]}`,
+		"c": `{"type":"accounts","payload":[{"id":3}]}`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pages[r.URL.Query().Get("next")]))
+		}))
+	defer s.Close()
+
+	cl := client.New(http.DefaultClient, s.URL, "user", "pass")
+	ids := []int64{}
+	it := cl.Accounts()
+	for it.Next() {
+		ids = append(ids, it.Account().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Fatalf("unexpected accounts %v", ids)
+	}
+}
+
+func TestAccountsScoped(t *testing.T) {
+	svc := service.New()
+	s := httptest.NewServer(svc)
+	defer s.Close()
+
+	// Every other account is allowed, so unfiltered pages would be half full.
+	ids := map[int64]bool{}
+	cred := service.Credential{User: "scoped", Pass: "secret"}
+	for i := 0; i < 120; i++ {
+		acc := svc.CreateAccount(service.MainNet, 0)
+		if i%2 == 0 {
+			ids[acc.ID] = true
+			cred.AccountIDs = append(cred.AccountIDs, acc.ID)
+		}
+	}
+	if err := svc.AddCredential(service.MainNet, cred); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := client.New(http.DefaultClient, s.URL+"/v1/mainnet", "scoped",
+		"secret")
+	it := cl.Accounts()
+	for it.Next() {
+		if !ids[it.Account().ID] {
+			t.Fatalf("unexpected account %d", it.Account().ID)
+		}
+		delete(ids, it.Account().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("%d accounts not iterated", len(ids))
+	}
+}
+
+func TestEmptyPayload(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"type":"accounts","payload":[]}`))
+		}))
+	defer s.Close()
+
+	cl := client.New(http.DefaultClient, s.URL, "user", "pass")
+	if _, err := cl.CreateAccount(); err != client.ErrEmptyPayload {
+		t.Fatalf("expected empty payload got %v", err)
+	}
+	if _, err := cl.Account(1); err != client.ErrEmptyPayload {
+		t.Fatalf("expected empty payload got %v", err)
+	}
+	if _, err := cl.CreateAddress(1); err != client.ErrEmptyPayload {
+		t.Fatalf("expected empty payload got %v", err)
+	}
+	if _, err := cl.Transaction(1); err != client.ErrEmptyPayload {
+		t.Fatalf("expected empty payload got %v", err)
+	}
+}
+
+func TestHooksAndFees(t *testing.T) {
+	cl, s := newClient()
+	defer s.Close()
+
+	const url = "https://example.com/hook"
+	if err := cl.CreateHook(url); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.CreateHook(url); client.ErrorCode(err) !=
+		client.HookExists {
+		t.Fatalf("expected hook exists got %v", err)
+	}
+	hooks, err := cl.Hooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0] != url {
+		t.Fatalf("unexpected hooks %v", hooks)
+	}
+	if err := cl.DeleteHook(url); err != nil {
+		t.Fatal(err)
+	}
+	if hooks, err := cl.Hooks(); err != nil || len(hooks) != 0 {
+		t.Fatalf("unexpected hooks %v %v", hooks, err)
+	}
+
+	fees, err := cl.Fees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fees) == 0 || fees[0].FeePerByte == 0 {
+		t.Fatalf("unexpected fees %+v", fees)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := httptest.NewServer(service.New(
+		service.Credentials(service.MainNet, service.Credential{
+			Token: "token",
+		})))
+	defer s.Close()
+
+	cl := client.NewWithToken(http.DefaultClient, s.URL+"/v1/mainnet/",
+		"token")
+	if _, err := cl.CreateAccount(); err != nil {
+		t.Fatal(err)
+	}
+
+	cl = client.New(http.DefaultClient, s.URL+"/v1/mainnet", "user", "x")
+	_, err := cl.CreateAccount()
+	e, ok := err.(*client.Error)
+	if !ok || e.StatusCode != http.StatusUnauthorized ||
+		e.Code != client.Unauthenticated {
+		t.Fatalf("expected unauthenticated got %v", err)
+	}
+}
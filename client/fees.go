@@ -0,0 +1,17 @@
+package client
+
+// Fee is an entry of the fee table, a fee per byte in satoshis and the block
+// height it was set at.
+type Fee struct {
+	FeePerByte  int64 `json:"feePerByte"`
+	BlockHeight int64 `json:"blockHeight"`
+}
+
+// Fees returns the fees per byte charged for debits.
+func (cl *Client) Fees() ([]Fee, error) {
+	fees := []Fee{}
+	if err := cl.do("GET", "/fees/", nil, &fees); err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
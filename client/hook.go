@@ -0,0 +1,33 @@
+package client
+
+import "encoding/base64"
+
+type hook struct {
+	URL string `json:"url"`
+}
+
+// CreateHook registers url to receive credit events as a POST request with a
+// JSON body of the transaction.
+func (cl *Client) CreateHook(url string) error {
+	return cl.do("POST", "/hooks/", hook{URL: url}, nil)
+}
+
+// Hooks returns the URLs of the registered hooks.
+func (cl *Client) Hooks() ([]string, error) {
+	hooks := []hook{}
+	if err := cl.do("GET", "/hooks/", nil, &hooks); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, len(hooks))
+	for i, h := range hooks {
+		urls[i] = h.URL
+	}
+	return urls, nil
+}
+
+// DeleteHook stops url from receiving credit events.
+func (cl *Client) DeleteHook(url string) error {
+	encoded := base64.URLEncoding.EncodeToString([]byte(url))
+	return cl.do("DELETE", "/hooks/"+encoded, nil, nil)
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Transaction types.
+const (
+	Transfer = "transfer"
+	Debit    = "debit"
+	Credit   = "credit"
+)
+
+// Transaction is a transfer between accounts, a debit to bitcoin addresses
+// or a credit from the bitcoin network.
+type Transaction struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+
+	// Status is "complete" for applied transactions and "unconfirmed" for
+	// credits that have not been mined. Reversed credits are "reversed".
+	// Transaction IDs that have not been used are "pending", "expired" or
+	// "cancelled".
+	Status string `json:"status"`
+
+	FromAccountID int64 `json:"fromAccountID"`
+	ToAccountID   int64 `json:"toAccountID"`
+
+	FromAccountBalance int64 `json:"fromAccountBalance"`
+	ToAccountBalance   int64 `json:"toAccountBalance"`
+
+	FromAccountTxID int64 `json:"fromAccountTxID"`
+	ToAccountTxID   int64 `json:"toAccountTxID"`
+
+	Value int64 `json:"value"`
+
+	Created time.Time `json:"created"`
+
+	TxHashes []string `json:"txHashes"`
+	TxIndex  int64    `json:"txIndex"`
+
+	Outputs []Output `json:"outputs"`
+	Fee     int64    `json:"fee"`
+
+	FromAddress   string `json:"fromAddress"`
+	Confirmations int64  `json:"confirmations"`
+}
+
+// Output is an address paid by a debit.
+type Output struct {
+	ToAddress string `json:"toAddress"`
+	Value     int64  `json:"value"`
+	TxIndex   int64  `json:"txIndex,omitempty"`
+}
+
+// CreateTransactionIDs returns n IDs, between 1 and 10, used to send
+// transactions. Resending a transaction with the same ID does not apply it
+// twice.
+func (cl *Client) CreateTransactionIDs(n int) ([]int64, error) {
+	pl := []struct {
+		ID int64 `json:"id"`
+	}{}
+	if err := cl.do("POST", "/transactions/", struct {
+		N int `json:"n"`
+	}{n}, &pl); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(pl))
+	for i, p := range pl {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+type putTransaction struct {
+	ID            int64    `json:"id"`
+	FromAccountID int64    `json:"fromAccountID"`
+	ToAccountID   int64    `json:"toAccountID,omitempty"`
+	ToAddress     string   `json:"toAddress,omitempty"`
+	Value         int64    `json:"value,omitempty"`
+	Outputs       []Output `json:"outputs,omitempty"`
+}
+
+// Transfer moves value from one account to another.
+func (cl *Client) Transfer(txID, fromAccountID, toAccountID,
+	value int64) error {
+	return cl.do("PUT", "/transactions/", putTransaction{
+		ID:            txID,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Value:         value,
+	}, nil)
+}
+
+// Debit sends value from an account to a bitcoin address.
+func (cl *Client) Debit(txID, fromAccountID int64, toAddress string,
+	value int64) error {
+	return cl.do("PUT", "/transactions/", putTransaction{
+		ID:            txID,
+		FromAccountID: fromAccountID,
+		ToAddress:     toAddress,
+		Value:         value,
+	}, nil)
+}
+
+// DebitOutputs sends funds from an account to several bitcoin addresses in
+// one on-chain transaction.
+func (cl *Client) DebitOutputs(txID, fromAccountID int64,
+	outputs []Output) error {
+	return cl.do("PUT", "/transactions/", putTransaction{
+		ID:            txID,
+		FromAccountID: fromAccountID,
+		Outputs:       outputs,
+	}, nil)
+}
+
+// Transaction returns the transaction id.
+func (cl *Client) Transaction(id int64) (Transaction, error) {
+	txns := []Transaction{}
+	path := fmt.Sprintf("/transactions/%d", id)
+	if err := cl.do("GET", path, nil, &txns); err != nil {
+		return Transaction{}, err
+	}
+	if len(txns) == 0 {
+		return Transaction{}, ErrEmptyPayload
+	}
+	return txns[0], nil
+}
+
+// CancelTransactionID prevents an unused transaction ID from being used.
+func (cl *Client) CancelTransactionID(id int64) error {
+	return cl.do("DELETE", fmt.Sprintf("/transactions/%d", id), nil, nil)
+}
+
+// TransactionIterator iterates over the transactions of an account, oldest
+// first, requesting them a page at a time.
+type TransactionIterator struct {
+	pager
+	page []Transaction
+	tx   Transaction
+}
+
+// Transactions returns an iterator over the transactions of an account.
+func (cl *Client) Transactions(accountID int64) *TransactionIterator {
+	return &TransactionIterator{pager: pager{
+		cl:   cl,
+		path: fmt.Sprintf("/accounts/%d/transactions/", accountID),
+	}}
+}
+
+// Next moves to the next transaction, returning false when there are none
+// left or an error occurred.
+func (it *TransactionIterator) Next() bool {
+	if len(it.page) == 0 {
+		it.page = []Transaction{}
+		if !it.fetch(&it.page, func() int { return len(it.page) }) {
+			return false
+		}
+	}
+	it.tx, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIterator) Transaction() Transaction {
+	return it.tx
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
@@ -5,19 +5,189 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/rtwire/mock/cassette"
 	"github.com/rtwire/mock/service"
 )
 
 var (
-	addr = flag.String("addr", ":8085", "service address")
+	addr    = flag.String("addr", ":8085", "service address")
+	tenants = flag.Bool("tenants", false,
+		"isolate clients by basic auth user name or X-Mock-Tenant header")
+	strict = flag.Bool("strict", false,
+		"reject requests the live API may not accept")
+	validate = flag.Bool("validate", false,
+		"check requests and responses against the OpenAPI document")
+	fixtures = flag.String("fixtures", "",
+		"YAML file of accounts, transactions, hooks and fees to load")
+	scenario = flag.String("scenario", "",
+		"YAML file of timed credits, blocks and reorgs to play")
+	record = flag.String("record", "",
+		"forward requests to this upstream url and record them")
+	cassetteFile = flag.String("cassette", "cassette.json",
+		"file recordings are written to on exit")
+	replay = flag.String("replay", "",
+		"cassette file whose recorded responses are served")
+	compare = flag.String("compare", "",
+		"cassette file whose requests are compared against the mock")
 )
 
 func main() {
 	flag.Parse()
 
+	switch {
+	case *record != "":
+		runRecorder()
+		return
+	case *replay != "":
+		runPlayer()
+		return
+	}
+
+	options := []service.Option{}
+	if *tenants {
+		options = append(options,
+			service.Tenants(service.MainNet),
+			service.Tenants(service.TestNet3))
+	}
+	if *strict {
+		options = append(options,
+			service.Strict(service.MainNet),
+			service.Strict(service.TestNet3))
+	}
+	if *validate {
+		options = append(options,
+			service.Validate(service.MainNet),
+			service.Validate(service.TestNet3))
+	}
+	if *fixtures != "" {
+		f, err := readFixtures(*fixtures)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options = append(options, service.Seed(f))
+	}
+	s := service.New(options...)
+
+	if *compare != "" {
+		runCompare(s)
+		return
+	}
+
+	if *scenario != "" {
+		sc, err := readScenario(*scenario)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := s.Play(sc); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	url := fmt.Sprintf("http://%s/v1/mainnet/", *addr)
 	log.Printf("RTWire service running at %s.", url)
+	log.Printf("Mock admin endpoints running at http://%s/_mock/mainnet/.", *addr)
+
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
+
+func readFixtures(name string) (service.Fixtures, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return service.ReadFixtures(file)
+}
+
+func readScenario(name string) (service.Scenario, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return service.Scenario{}, err
+	}
+	defer file.Close()
+	return service.ReadScenario(file)
+}
+
+func readCassette(name string) (*cassette.Cassette, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return cassette.ReadCassette(file)
+}
+
+func writeCassette(name string, c *cassette.Cassette) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runRecorder forwards requests upstream until interrupted and then writes
+// the recorded cassette.
+func runRecorder() {
+	rec, err := cassette.NewRecorder(http.DefaultClient, *record)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		log.Fatal(http.ListenAndServe(*addr, rec))
+	}()
+	log.Printf("Recording requests to http://%s/ forwarded to %s.", *addr,
+		*record)
+
+	<-interrupt
+	c := rec.Cassette()
+	if err := writeCassette(*cassetteFile, c); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d interactions to %s.", len(c.Interactions),
+		*cassetteFile)
+}
+
+func runPlayer() {
+	c, err := readCassette(*replay)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Replaying %d interactions from %s at http://%s/.",
+		len(c.Interactions), *replay, *addr)
+	log.Fatal(http.ListenAndServe(*addr, cassette.NewPlayer(c)))
+}
+
+// runCompare makes the requests of a cassette to s, printing the differences,
+// and exits with status 1 if there are any.
+func runCompare(s *service.Service) {
+	c, err := readCassette(*compare)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	log.Fatal(http.ListenAndServe(*addr, service.New()))
+	diffs := cassette.Compare(c, s, func(r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/_mock/") {
+			r.SetBasicAuth("admin", "pass")
+		} else {
+			r.SetBasicAuth("user", "pass")
+		}
+	})
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,99 @@
+package mocktest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/client"
+)
+
+// HookTimeout is how long ExpectHook waits for an event.
+var HookTimeout = 5 * time.Second
+
+// hookRecorder is a hook that records the credit events sent by the mock.
+type hookRecorder struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	events  []client.Transaction
+	arrived chan struct{}
+}
+
+func (h *hookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	msg := struct {
+		Payload []client.Transaction `json:"payload"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, msg.Payload...)
+	close(h.arrived)
+	h.arrived = make(chan struct{})
+}
+
+// find returns the event of the credit txID, if it has arrived, and a channel
+// closed when the next event arrives.
+func (h *hookRecorder) find(txID int64) (client.Transaction, bool,
+	<-chan struct{}) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, tx := range h.events {
+		if tx.ID == txID {
+			return tx, true, nil
+		}
+	}
+	return client.Transaction{}, false, h.arrived
+}
+
+// ListenHooks registers a hook that records the credit events sent by the
+// mock, so that they can be waited for with ExpectHook. It must be called
+// before the credits are made.
+func (m *Mock) ListenHooks(t testing.TB) {
+	t.Helper()
+
+	if m.hooks != nil {
+		return
+	}
+	h := &hookRecorder{arrived: make(chan struct{})}
+	h.server = httptest.NewServer(h)
+	t.Cleanup(h.server.Close)
+
+	if err := m.Client.CreateHook(h.server.URL); err != nil {
+		t.Fatal(err)
+	}
+	m.hooks = h
+}
+
+// ExpectHook waits for the hook event of the credit txID and returns it. It
+// fails the test if the event does not arrive within HookTimeout.
+func (m *Mock) ExpectHook(t testing.TB, txID int64) client.Transaction {
+	t.Helper()
+
+	if m.hooks == nil {
+		t.Fatal("ListenHooks must be called before ExpectHook")
+	}
+
+	timeout := time.After(HookTimeout)
+	for {
+		tx, found, arrived := m.hooks.find(txID)
+		if found {
+			return tx
+		}
+		select {
+		case <-arrived:
+		case <-timeout:
+			t.Fatalf("hook event of transaction %d not received", txID)
+		}
+	}
+}
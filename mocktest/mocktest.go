@@ -0,0 +1,208 @@
+// Package mocktest runs the mock RTWire service for Go tests and provides
+// fixtures to set up and check its state, for example:
+//
+//	func TestPayout(t *testing.T) {
+//	    m := mocktest.New(t)
+//	    m.ListenHooks(t)
+//
+//	    acc := m.FundedAccount(t, 5000)
+//	    payout(m.Client, acc.ID)
+//
+//	    m.AssertBalance(t, acc.ID, 0)
+//	}
+package mocktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtwire/mock/client"
+	"github.com/rtwire/mock/service"
+)
+
+// Mock is a mock service running on an httptest.Server. Fixtures use the
+// main network with the default credentials.
+type Mock struct {
+	Server *httptest.Server
+
+	// URL is the URL of the main network, such as
+	// http://127.0.0.1:1234/v1/mainnet.
+	URL string
+
+	// Client sends requests to the main network.
+	Client *client.Client
+
+	hooks *hookRecorder
+}
+
+// New starts a mock service created by service.New(). It is closed when the
+// test finishes.
+func New(t testing.TB) *Mock {
+	return Serve(t, service.New())
+}
+
+// Serve starts h, a mock service created with options, and closes it when the
+// test finishes. The service must use the default credentials.
+func Serve(t testing.TB, h http.Handler) *Mock {
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	url := s.URL + "/v1/mainnet"
+	return &Mock{
+		Server: s,
+		URL:    url,
+		Client: client.New(http.DefaultClient, url, "user", "pass"),
+	}
+}
+
+// admin sends a request to a /_mock/mainnet/ endpoint and decodes the payload
+// of the response into payload, if not nil.
+func (m *Mock) admin(t testing.TB, method, path string,
+	body, payload interface{}) {
+
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := http.NewRequest(method, m.Server.URL+"/_mock/mainnet"+path,
+		&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth("admin", "pass")
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode >= 400 {
+		t.Fatalf("%s %s: %d %s", method, path, resp.StatusCode, resBody)
+	}
+	if payload == nil {
+		return
+	}
+
+	msg := struct {
+		Payload json.RawMessage `json:"payload"`
+	}{}
+	if err := json.Unmarshal(resBody, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(msg.Payload, payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Account creates an account.
+func (m *Mock) Account(t testing.TB) client.Account {
+	t.Helper()
+
+	acc, err := m.Client.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc
+}
+
+// Address creates an address for the account accID.
+func (m *Mock) Address(t testing.TB, accID int64) string {
+	t.Helper()
+
+	addr, err := m.Client.CreateAddress(accID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+// Credit credits addr with value in a confirmed on-chain transaction and
+// returns the credit.
+func (m *Mock) Credit(t testing.TB, addr string,
+	value int64) client.Transaction {
+
+	t.Helper()
+
+	txns := []client.Transaction{}
+	m.admin(t, "POST", "/addresses/"+addr, struct {
+		Value int64 `json:"value"`
+	}{value}, &txns)
+	return txns[0]
+}
+
+// FundedAccount creates an account with an address credited with value.
+func (m *Mock) FundedAccount(t testing.TB, value int64) client.Account {
+	t.Helper()
+
+	acc := m.Account(t)
+	m.Credit(t, m.Address(t, acc.ID), value)
+	acc.Balance = value
+	return acc
+}
+
+// Transfer moves value between accounts and returns the transaction ID.
+func (m *Mock) Transfer(t testing.TB, fromAccID, toAccID,
+	value int64) int64 {
+
+	t.Helper()
+
+	ids, err := m.Client.CreateTransactionIDs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Client.Transfer(ids[0], fromAccID, toAccID,
+		value); err != nil {
+		t.Fatal(err)
+	}
+	return ids[0]
+}
+
+// Mine mines n blocks, the first of which confirms unconfirmed credits.
+func (m *Mock) Mine(t testing.TB, n int) {
+	t.Helper()
+
+	m.admin(t, "POST", "/blocks/", struct {
+		N int `json:"n"`
+	}{n}, nil)
+}
+
+// Reset returns the mock to its initial state.
+func (m *Mock) Reset(t testing.TB) {
+	t.Helper()
+
+	m.admin(t, "POST", "/reset/", nil, nil)
+}
+
+// AssertBalance reports an error to t, and returns false, unless the account
+// accID has balance.
+func (m *Mock) AssertBalance(t testing.TB, accID, balance int64) bool {
+	t.Helper()
+
+	acc, err := m.Client.Account(accID)
+	if err != nil {
+		t.Errorf("account %d: %v", accID, err)
+		return false
+	}
+	if acc.Balance != balance {
+		t.Errorf("expected account %d balance %d got %d", accID, balance,
+			acc.Balance)
+		return false
+	}
+	return true
+}
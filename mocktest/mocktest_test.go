@@ -0,0 +1,64 @@
+package mocktest_test
+
+import (
+	"testing"
+
+	"github.com/rtwire/mock/client"
+	"github.com/rtwire/mock/mocktest"
+	"github.com/rtwire/mock/service"
+)
+
+func TestFixtures(t *testing.T) {
+	m := mocktest.New(t)
+	m.ListenHooks(t)
+
+	from := m.FundedAccount(t, 5000)
+	to := m.FundedAccount(t, 1000)
+	m.AssertBalance(t, from.ID, 5000)
+
+	m.Transfer(t, from.ID, to.ID, 2000)
+	m.AssertBalance(t, from.ID, 3000)
+	m.AssertBalance(t, to.ID, 3000)
+
+	credit := m.Credit(t, m.Address(t, to.ID), 500)
+	tx := m.ExpectHook(t, credit.ID)
+	if tx.Type != client.Credit || tx.Value != 500 ||
+		tx.ToAccountID != to.ID {
+		t.Fatalf("unexpected hook event %+v", tx)
+	}
+	m.AssertBalance(t, to.ID, 3500)
+
+	m.Reset(t)
+	if _, err := m.Client.Account(to.ID); client.ErrorCode(err) !=
+		client.AccountNotFound {
+		t.Fatalf("expected account not found got %v", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	m := mocktest.Serve(t, service.New(service.Strict(service.MainNet)))
+
+	acc := m.FundedAccount(t, 100)
+	m.Mine(t, 1)
+	m.AssertBalance(t, acc.ID, 100)
+}
+
+// recorder records errors reported by AssertBalance.
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
+
+func TestAssertBalance(t *testing.T) {
+	m := mocktest.New(t)
+	acc := m.FundedAccount(t, 100)
+
+	r := &recorder{TB: t}
+	if m.AssertBalance(r, acc.ID, 99) || r.errors != 1 {
+		t.Fatal("expected AssertBalance to fail")
+	}
+}
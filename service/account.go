@@ -1,7 +1,6 @@
 package service
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -13,9 +12,54 @@ const (
 	getAccountsLimitMax = 50
 )
 
-type accountPayload struct {
+var (
+	errInvalidAccountID = newError("invalid_account_id",
+		"invalid account id")
+	errInvalidNext = newError("invalid_next", "next must be >= 0")
+)
+
+// pageParams returns the limit and next query parameters of r, which select a
+// page of a list.
+func pageParams(r *http.Request) (int, int, error) {
+	limit := getAccountsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > getAccountsLimitMax {
+			return 0, 0, errorf("invalid_limit",
+				"limit must be between 0 and %d", getAccountsLimitMax)
+		}
+		limit = n
+	}
+
+	next := 0
+	if value := r.URL.Query().Get("next"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, errInvalidNext
+		}
+		next = n
+	}
+	return limit, next, nil
+}
+
+// nextPage returns the next query parameter selecting the page after the n
+// items returned for limit and next, or "" if that was the last page.
+func nextPage(limit, next, n int) string {
+	if n == 0 || n < limit {
+		return ""
+	}
+	return strconv.Itoa(next + n)
+}
+
+// Account is an account as returned by the /accounts/ endpoints.
+type Account struct {
 	ID      int64 `json:"id"`
 	Balance int64 `json:"balance"`
+	Frozen  bool  `json:"frozen,omitempty"`
+}
+
+func newAccount(acc account) Account {
+	return Account{acc.id, acc.balance, acc.frozen}
 }
 
 func (c *chain) postAccountsHandler(w http.ResponseWriter, r *http.Request) {
@@ -24,12 +68,15 @@ func (c *chain) postAccountsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	acc := c.CreateAccount()
+	if requestCredential(r).scoped() {
+		sendError(w, http.StatusForbidden, errAccountForbidden)
+		return
+	}
+
+	acc := c.CreateAccount(0)
 
 	sendPayload(w, http.StatusCreated, "accounts", "",
-		[]accountPayload{
-			{acc.id, acc.balance},
-		})
+		[]Account{newAccount(acc)})
 }
 
 func (c *chain) getAccountsHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,40 +85,20 @@ func (c *chain) getAccountsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limitValue := r.URL.Query().Get("limit")
-	limit, err := strconv.Atoi(limitValue)
-	if limitValue == "" {
-		limit = getAccountsLimit
-	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	} else if limit > getAccountsLimitMax {
-		errStr := fmt.Sprintf("limit > %d", getAccountsLimitMax)
-		http.Error(w, errStr, http.StatusBadRequest)
+	limit, next, err := pageParams(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	nextValue := r.URL.Query().Get("next")
-	next, err := strconv.Atoi(nextValue)
-	if nextValue == "" {
-		next = 0
-	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	accountsPayload := []Account{}
+	allows := func(accID int64) bool { return allowsAccount(r, accID) }
+	for _, acc := range c.Accounts(limit, next, allows) {
+		accountsPayload = append(accountsPayload, newAccount(acc))
 	}
 
-	accountsPayload := []accountPayload{}
-	for i, acc := range c.Accounts(limit, next) {
-		if i < next {
-			continue
-		}
-		accountsPayload = append(accountsPayload, accountPayload{
-			ID:      acc.id,
-			Balance: acc.balance,
-		})
-	}
-
-	sendPayload(w, http.StatusOK, "accounts", "", accountsPayload)
+	sendPayload(w, http.StatusOK, "accounts",
+		nextPage(limit, next, len(accountsPayload)), accountsPayload)
 }
 
 func (c *chain) getAccountByLabelHandler(w http.ResponseWriter,
@@ -85,16 +112,18 @@ func (c *chain) getAccountByLabelHandler(w http.ResponseWriter,
 
 	acc, exists := c.AccountByLabel(accLabel)
 	if !exists {
-		errStr := fmt.Sprintf(
-			"account with label %v not found", accLabel)
-		http.Error(w, errStr, http.StatusNotFound)
+		sendError(w, http.StatusNotFound, errorf("account_not_found",
+			"account with label %v not found", accLabel))
+		return
+	}
+
+	if !allowsAccount(r, acc.id) {
+		sendError(w, http.StatusForbidden, errAccountForbidden)
 		return
 	}
 
 	sendPayload(w, http.StatusOK, "accounts", "",
-		[]accountPayload{
-			{acc.id, acc.balance},
-		})
+		[]Account{newAccount(acc)})
 }
 
 func (c *chain) getAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -106,21 +135,24 @@ func (c *chain) getAccountHandler(w http.ResponseWriter, r *http.Request) {
 	accIDValue := mux.Vars(r)["account-id"]
 	accID, err := strconv.ParseInt(accIDValue, 10, 64)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, errInvalidAccountID)
 		return
 	}
 
 	acc, exists := c.Account(accID)
 	if !exists {
-		errStr := fmt.Sprintf("account with ID %v not found", accID)
-		http.Error(w, errStr, http.StatusNotFound)
+		sendError(w, http.StatusNotFound, errorf("account_not_found",
+			"account with ID %v not found", accID))
+		return
+	}
+
+	if !allowsAccount(r, acc.id) {
+		sendError(w, http.StatusForbidden, errAccountForbidden)
 		return
 	}
 
 	sendPayload(w, http.StatusOK, "accounts", "",
-		[]accountPayload{
-			{acc.id, acc.balance},
-		})
+		[]Account{newAccount(acc)})
 }
 
 type addressPayload struct {
@@ -136,17 +168,22 @@ func (c *chain) postAccountAddresses(w http.ResponseWriter, r *http.Request) {
 	accIDValue := mux.Vars(r)["account-id"]
 	accID, err := strconv.ParseInt(accIDValue, 10, 64)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, errInvalidAccountID)
+		return
+	}
+
+	if !allowsAccount(r, accID) {
+		sendError(w, http.StatusForbidden, errAccountForbidden)
 		return
 	}
 
 	addr, err := c.CreateAddress(accID)
 	if err == errAccountNotFound {
-		errStr := fmt.Sprintf("account ID %v not found", accID)
-		http.Error(w, errStr, http.StatusNotFound)
+		sendError(w, http.StatusNotFound, errorf("account_not_found",
+			"account ID %v not found", accID))
 		return
 	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -155,3 +192,35 @@ func (c *chain) postAccountAddresses(w http.ResponseWriter, r *http.Request) {
 			{Address: addr},
 		})
 }
+
+// CreateAccount creates an account on network with balance. No transaction is
+// recorded for the balance, which makes seeding many accounts much faster
+// than crediting them.
+func (s *Service) CreateAccount(network Network, balance int64) Account {
+	return newAccount(s.chain(network).CreateAccount(balance))
+}
+
+// SetLabel replaces the label of the account accID of network. An empty label
+// removes it. Labels are unique within a network.
+func (s *Service) SetLabel(network Network, accID int64, label string) error {
+	return s.chain(network).SetLabel(accID, label)
+}
+
+// Account returns the account id of network.
+func (s *Service) Account(network Network, id int64) (Account, bool) {
+	acc, exists := s.chain(network).Account(id)
+	return newAccount(acc), exists
+}
+
+// AccountByLabel returns the account of network with label.
+func (s *Service) AccountByLabel(network Network,
+	label string) (Account, bool) {
+
+	acc, exists := s.chain(network).AccountByLabel(label)
+	return newAccount(acc), exists
+}
+
+// CreateAddress creates an address of network for the account accID.
+func (s *Service) CreateAddress(network Network, accID int64) (string, error) {
+	return s.chain(network).CreateAddress(accID)
+}
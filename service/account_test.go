@@ -125,3 +125,57 @@ func TestAccountAddresses(t *testing.T) {
 		t.Fatalf("expected %v got %v", http.StatusCreated, w.Code)
 	}
 }
+
+func TestServiceAccounts(t *testing.T) {
+	s := service.New()
+
+	acc := s.CreateAccount(service.MainNet, 700)
+	if b := balance(t, s, acc.ID); b != 700 {
+		t.Fatalf("expected balance 700 got %d", b)
+	}
+
+	// Accounts created with a negative balance are frozen.
+	if overdrawn := s.CreateAccount(service.MainNet, -10); !overdrawn.Frozen {
+		t.Fatalf("expected frozen account got %+v", overdrawn)
+	}
+
+	if err := s.SetLabel(service.MainNet, acc.ID, "hot"); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.AccountByLabel(service.MainNet, "hot"); !ok ||
+		got != acc {
+		t.Fatalf("expected account %+v got %+v", acc, got)
+	}
+	if err := s.SetLabel(service.MainNet, acc.ID, "_fee"); err == nil {
+		t.Fatal("expected label exists error")
+	}
+
+	// Relabelling an account removes its old label.
+	if err := s.SetLabel(service.MainNet, acc.ID, "cold"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.AccountByLabel(service.MainNet, "hot"); ok {
+		t.Fatal("expected old label to be removed")
+	}
+
+	if err := s.SetLabel(service.MainNet, 999, "x"); err == nil {
+		t.Fatal("expected account not found error")
+	}
+	if _, ok := s.Account(service.TestNet3, acc.ID); ok {
+		t.Fatal("expected account to only exist on mainnet")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected unknown network to panic")
+		}
+	}()
+	s.CreateAccount("regtest", 0)
+}
+
+func BenchmarkCreateAccount(b *testing.B) {
+	s := service.New()
+	for i := 0; i < b.N; i++ {
+		s.CreateAccount(service.MainNet, 1000)
+	}
+}
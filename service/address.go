@@ -2,41 +2,202 @@ package service
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/gorilla/mux"
 )
 
+const maxCreditConfirmations = 100
+
+type creditPayload struct {
+	Value int64  `json:"value"`
+	Vout  *int64 `json:"vout"`
+
+	TxHash        string `json:"txHash"`
+	FromAddress   string `json:"fromAddress"`
+	Confirmations *int64 `json:"confirmations"`
+
+	// Outputs credits several addresses within the same on-chain
+	// transaction.
+	Outputs []creditOutputPayload `json:"outputs"`
+
+	// RawTx is a hex encoded serialized Bitcoin transaction. Every output
+	// paying an address owned by the mock is credited.
+	RawTx string `json:"rawTx"`
+}
+
+type creditOutputPayload struct {
+	Address string `json:"address"`
+	Value   int64  `json:"value"`
+	Vout    *int64 `json:"vout"`
+}
+
+// postAddressHandler credits the address in the URL as if it had received
+// bitcoins from the network.
 func (c *chain) postAddressHandler(w http.ResponseWriter, r *http.Request) {
 
 	if !contentTypeHeaderFound(w, r) {
 		return
 	}
 
-	pl := struct {
-		Value int64 `json:"value"`
-	}{}
+	pl := creditPayload{}
 
-	if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	addr := mux.Vars(r)["address"]
+	if len(pl.Outputs) > 0 || pl.RawTx != "" {
+		sendError(w, http.StatusBadRequest, newError("field_not_allowed",
+			"outputs and rawTx not allowed"))
+		return
+	}
 
-	txID, exists := c.Credit(addr, pl.Value)
-	if !exists {
-		http.Error(w, "address not found", http.StatusBadRequest)
+	pl.Outputs = []creditOutputPayload{{
+		Address: mux.Vars(r)["address"],
+		Value:   pl.Value,
+		Vout:    pl.Vout,
+	}}
+	c.handleCredit(w, pl)
+}
+
+// postAddressesHandler credits the outputs of a simulated on-chain
+// transaction.
+func (c *chain) postAddressesHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !contentTypeHeaderFound(w, r) {
 		return
 	}
 
-	if err := c.sendHookCreditEvent(txID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	pl := creditPayload{}
+
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if pl.RawTx != "" {
+		if len(pl.Outputs) > 0 || pl.TxHash != "" {
+			sendError(w, http.StatusBadRequest, newError("field_not_allowed",
+				"rawTx not allowed with outputs or txHash"))
+			return
+		}
+		if err := c.decodeRawTx(&pl); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if len(pl.Outputs) == 0 {
+		sendError(w, http.StatusBadRequest, errNoOutputs)
+		return
+	}
+	c.handleCredit(w, pl)
+}
+
+// decodeRawTx sets the txHash and outputs of pl from those outputs of
+// pl.RawTx that pay addresses owned by the chain.
+func (c *chain) decodeRawTx(pl *creditPayload) error {
+	raw, err := hex.DecodeString(pl.RawTx)
+	if err != nil {
+		return errors.New("rawTx not hex encoded")
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return errors.New("invalid rawTx")
+	}
+
+	pl.TxHash = tx.TxHash().String()
+	for i, out := range tx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.PkScript,
+			c.params())
+		if err != nil || len(addrs) != 1 {
+			continue
+		}
+		addr := addrs[0].EncodeAddress()
+		if _, exists := c.AddressAccount(addr); !exists {
+			continue
+		}
+		vout := int64(i)
+		pl.Outputs = append(pl.Outputs, creditOutputPayload{
+			Address: addr,
+			Value:   out.Value,
+			Vout:    &vout,
+		})
+	}
+
+	if len(pl.Outputs) == 0 {
+		return errors.New("rawTx pays no addresses")
+	}
+	return nil
+}
+
+func (c *chain) handleCredit(w http.ResponseWriter, pl creditPayload) {
+
+	if pl.TxHash != "" {
+		if _, err := chainhash.NewHashFromStr(pl.TxHash); err != nil {
+			sendError(w, http.StatusBadRequest,
+				newError("invalid_tx_hash", "invalid txHash"))
+			return
+		}
+	}
+
+	in := incomingTx{
+		txHash:        pl.TxHash,
+		fromAddress:   pl.FromAddress,
+		confirmations: 1,
+	}
+
+	if pl.Confirmations != nil {
+		in.confirmations = *pl.Confirmations
+	}
+
+	for i, out := range pl.Outputs {
+		vout := int64(i)
+		if out.Vout != nil {
+			vout = *out.Vout
+		}
+		if vout < 0 {
+			sendError(w, http.StatusBadRequest,
+				newError("invalid_vout", "invalid vout"))
+			return
+		}
+		in.outputs = append(in.outputs, output{
+			address: out.Address,
+			value:   out.Value,
+			txIndex: vout,
+		})
+	}
+
+	txIDs, err := c.Credit(in)
+	if err == errAddressNotFound {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	} else if err == errOutputCredited {
+		sendError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
+
+	c.sendTransactions(w, txIDs)
+}
+
+func (c *chain) sendHookCreditEvents(txIDs []int64) {
+	for _, txID := range txIDs {
+		if err := c.sendHookCreditEvent(txID); err != nil {
+			log.Printf("Error sending credit event %d: %v.", txID, err)
+		}
+	}
 }
 
 func (c *chain) sendHookCreditEvent(txID int64) error {
@@ -48,22 +209,16 @@ func (c *chain) sendHookCreditEvent(txID int64) error {
 
 	var body bytes.Buffer
 	if err := json.NewEncoder(&body).Encode(jsonMessage{
-		Type: "transactions",
-		Payload: []transactionPayload{{
-			ID:          txID,
-			Type:        tx.ty,
-			ToAccountID: tx.toAccountID,
-			Value:       tx.value,
-			Created:     tx.created,
-			TxHashes:    []string{},
-		}},
+		Type:    "transactions",
+		Payload: []Transaction{newTransaction(tx)},
 	}); err != nil {
 		return err
 	}
 
 	for _, url := range c.Hooks() {
 		go func(hook string) {
-			res, err := http.Post(url, "application/json", &body)
+			res, err := http.Post(hook, "application/json",
+				bytes.NewReader(body.Bytes()))
 			if err != nil {
 				log.Printf("Error sending to hook %s: %v.", hook, err)
 				return
@@ -76,3 +231,22 @@ func (c *chain) sendHookCreditEvent(txID int64) error {
 	}
 	return nil
 }
+
+// Credit credits addr on network with value in an on-chain transaction with
+// confirmations, as if received from the network, and returns the credit.
+// Hooks are sent the credit once it is confirmed. confirmations must be
+// between 0 and 100.
+func (s *Service) Credit(network Network, addr string,
+	value, confirmations int64) (Transaction, error) {
+
+	c := s.chain(network)
+	txIDs, err := c.Credit(incomingTx{
+		confirmations: confirmations,
+		outputs:       []output{{address: addr, value: value}},
+	})
+	if err != nil {
+		return Transaction{}, err
+	}
+	tx, _ := c.Transaction(txIDs[0])
+	return newTransaction(tx), nil
+}
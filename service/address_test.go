@@ -2,12 +2,17 @@ package service_test
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/rtwire/mock/service"
 )
 
@@ -117,3 +122,175 @@ func TestCreditFeeAddress(t *testing.T) {
 		t.Fatal("incrrect balance", feeAccPayload.Payload[0].Balance)
 	}
 }
+
+func TestCreditOutputs(t *testing.T) {
+	s := service.New()
+
+	accIDs := []int64{createAccount(t, s), createAccount(t, s)}
+	addrs := []string{createAddress(t, s, accIDs[0]),
+		createAddress(t, s, accIDs[1])}
+
+	const txHash = "4a5e1e4baab89f3a32518a88c31bc87f" +
+		"618f76673e2cc77ab2127b7afdeda33b"
+
+	type output struct {
+		Address string `json:"address"`
+		Value   int64  `json:"value"`
+		Vout    int64  `json:"vout"`
+	}
+	credit := struct {
+		TxHash        string   `json:"txHash"`
+		FromAddress   string   `json:"fromAddress"`
+		Confirmations int64    `json:"confirmations"`
+		Outputs       []output `json:"outputs"`
+	}{
+		TxHash:        txHash,
+		FromAddress:   "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		Confirmations: 0,
+		Outputs: []output{
+			{Address: addrs[0], Value: 100, Vout: 1},
+			{Address: addrs[1], Value: 200, Vout: 3},
+		},
+	}
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/addresses/", credit)
+	expectCode(t, w, http.StatusOK)
+
+	type txns struct {
+		Payload []struct {
+			ID            int64
+			Status        string
+			TxHashes      []string
+			TxIndex       int64
+			Confirmations int64
+		}
+	}
+	res := txns{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != 2 {
+		t.Fatalf("expected 2 credits got %d", len(res.Payload))
+	}
+	for i, tx := range res.Payload {
+		if tx.Status != "unconfirmed" || tx.TxHashes[0] != txHash ||
+			tx.TxIndex != credit.Outputs[i].Vout {
+			t.Fatalf("unexpected credit %+v", tx)
+		}
+	}
+	creditTxID := res.Payload[1].ID
+
+	// Unconfirmed credits do not change balances.
+	if b := balance(t, s, accIDs[0]); b != 0 {
+		t.Fatalf("expected balance 0 got %d", b)
+	}
+
+	// The same transaction can only be credited once.
+	w = serveAdmin(s, "POST", "/_mock/mainnet/addresses/", credit)
+	expectCode(t, w, http.StatusConflict)
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/blocks/", struct {
+		N int `json:"n"`
+	}{
+		N: 2,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	if b := balance(t, s, accIDs[0]); b != 100 {
+		t.Fatalf("expected balance 100 got %d", b)
+	}
+	if b := balance(t, s, accIDs[1]); b != 200 {
+		t.Fatalf("expected balance 200 got %d", b)
+	}
+
+	// Other outputs of the transaction can be credited separately and are
+	// confirmed by its block, but each output only once.
+	credit.Outputs = []output{{Address: addrs[0], Value: 50, Vout: 2}}
+	w = serveAdmin(s, "POST", "/_mock/mainnet/addresses/", credit)
+	expectCode(t, w, http.StatusOK)
+	if b := balance(t, s, accIDs[0]); b != 150 {
+		t.Fatalf("expected balance 150 got %d", b)
+	}
+	credit.Outputs[0].Vout = 3
+	w = serveAdmin(s, "POST", "/_mock/mainnet/addresses/", credit)
+	expectErrorCode(t, w, http.StatusConflict, "output_credited")
+
+	url := fmt.Sprintf("/v1/mainnet/transactions/%d", creditTxID)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	res = txns{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if tx := res.Payload[0]; tx.Status != "complete" ||
+		tx.Confirmations != 2 {
+		t.Fatalf("unexpected credit %+v", tx)
+	}
+}
+
+func TestCreditRawTx(t *testing.T) {
+	s := service.New()
+
+	accID := createAccount(t, s)
+	addr, err := btcutil.DecodeAddress(createAddress(t, s, accID),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreignAddr, err := btcutil.DecodeAddress(
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	for _, out := range []struct {
+		addr  btcutil.Address
+		value int64
+	}{
+		{foreignAddr, 5000},
+		{addr, 1500},
+	} {
+		script, err := txscript.PayToAddrScript(out.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.AddTxOut(wire.NewTxOut(out.value, script))
+	}
+
+	var raw bytes.Buffer
+	if err := tx.Serialize(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/addresses/", struct {
+		RawTx string `json:"rawTx"`
+	}{
+		RawTx: hex.EncodeToString(raw.Bytes()),
+	})
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Value    int64
+			TxHashes []string
+			TxIndex  int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != 1 {
+		t.Fatalf("expected 1 credit got %d", len(res.Payload))
+	}
+	if credit := res.Payload[0]; credit.Value != 1500 ||
+		credit.TxHashes[0] != tx.TxHash().String() || credit.TxIndex != 1 {
+		t.Fatalf("unexpected credit %+v", credit)
+	}
+
+	if b := balance(t, s, accID); b != 1500 {
+		t.Fatalf("expected balance 1500 got %d", b)
+	}
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// copy returns a deep copy of the ledger.
+func (l ledger) copy() ledger {
+	cp := newLedger()
+
+	for id, acc := range l.accounts {
+		cp.accounts[id] = acc
+	}
+	cp.orderedAccountIDs = append([]int64{}, l.orderedAccountIDs...)
+	for label, id := range l.accountLabels {
+		cp.accountLabels[label] = id
+	}
+
+	for addr, accID := range l.addresses {
+		cp.addresses[addr] = accID
+	}
+
+	for id, tx := range l.transactions {
+		cp.transactions[id] = tx
+	}
+	for id, created := range l.unusedTxIDs {
+		cp.unusedTxIDs[id] = created
+	}
+	for id := range l.cancelledTxIDs {
+		cp.cancelledTxIDs[id] = struct{}{}
+	}
+	cp.orderedTransactionIDs = append([]int64{}, l.orderedTransactionIDs...)
+
+	for url := range l.hooks {
+		cp.hooks[url] = struct{}{}
+	}
+
+	cp.blocks = make([]block, len(l.blocks))
+	for i, b := range l.blocks {
+		b.txHashes = append([]string(nil), b.txHashes...)
+		cp.blocks[i] = b
+	}
+	cp.mempool = append([]string{}, l.mempool...)
+	for txHash, height := range l.txHeights {
+		cp.txHeights[txHash] = height
+	}
+	for txHash, txIDs := range l.txCredits {
+		cp.txCredits[txHash] = append([]int64{}, txIDs...)
+	}
+
+	for id := range l.ids {
+		cp.ids[id] = struct{}{}
+	}
+	return cp
+}
+
+// Reset returns the chain to the state it was in when the service was
+// created. Stubs, the journal and any change to the time are also cleared and
+// the fault rules and rate limits given to New are restored. Snapshots are
+// kept.
+func (c *chain) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ledger = c.initial.copy()
+	c.setClockOffset(0)
+	c.faults = make([]*fault, len(c.initialFaults))
+	for i, rule := range c.initialFaults {
+		c.faults[i] = &fault{rule: rule}
+	}
+	c.setRateLimits(c.initialRateLimits)
+	c.stubs = nil
+	c.journal = nil
+}
+
+// Snapshot saves the accounts, addresses, transactions, hooks and blocks of
+// the chain and returns an ID that can be passed to Restore.
+func (c *chain) Snapshot() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextSnapshotID++
+	c.snapshots[c.nextSnapshotID] = c.ledger.copy()
+	return c.nextSnapshotID
+}
+
+var errSnapshotNotFound = newError("snapshot_not_found", "snapshot not found")
+
+// Restore returns the chain to the state saved by Snapshot. A snapshot can be
+// restored more than once.
+func (c *chain) Restore(id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, exists := c.snapshots[id]
+	if !exists {
+		return errSnapshotNotFound
+	}
+	c.ledger = l.copy()
+	return nil
+}
+
+func (c *chain) DeleteSnapshot(id int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.snapshots[id]; !exists {
+		return false
+	}
+	delete(c.snapshots, id)
+	return true
+}
+
+func (c *chain) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now()
+}
+
+// SetTime moves the time of the chain to t. Transaction IDs expire and
+// transactions are created according to the chain's time.
+func (c *chain) SetTime(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setClockOffset(t.Sub(time.Now()))
+}
+
+// Advance moves the time of the chain forward by d.
+func (c *chain) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setClockOffset(c.clockOffset + d)
+}
+
+// setClockOffset sets the offset of the chain's time from the current time
+// and closes the channel returned by clock. c.mu must be held.
+func (c *chain) setClockOffset(d time.Duration) {
+	c.clockOffset = d
+	close(c.clockMoved)
+	c.clockMoved = make(chan struct{})
+}
+
+// clock returns the time of the chain and a channel closed when it is next
+// moved by SetTime, Advance or Reset.
+func (c *chain) clock() (time.Time, <-chan struct{}) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now(), c.clockMoved
+}
+
+// StateAccount is an account with its label and addresses.
+type StateAccount struct {
+	Account
+	Label     string   `json:"label,omitempty"`
+	Addresses []string `json:"addresses"`
+}
+
+// State is every account, transaction, hook and block of a network.
+type State struct {
+	Time         time.Time      `json:"time"`
+	Accounts     []StateAccount `json:"accounts"`
+	Transactions []Transaction  `json:"transactions"`
+	Hooks        []string       `json:"hooks"`
+	Blocks       []Block        `json:"blocks"`
+	Mempool      []string       `json:"mempool"`
+}
+
+// State returns every account, transaction, hook and block of the chain.
+func (c *chain) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	labels := make(map[int64]string)
+	for label, id := range c.accountLabels {
+		labels[id] = label
+	}
+	addresses := make(map[int64][]string)
+	for addr, accID := range c.addresses {
+		addresses[accID] = append(addresses[accID], addr)
+	}
+
+	pl := State{
+		Time:         c.now(),
+		Accounts:     make([]StateAccount, len(c.orderedAccountIDs)),
+		Transactions: make([]Transaction, len(c.orderedTransactionIDs)),
+		Hooks:        []string{},
+		Blocks:       make([]Block, len(c.blocks)),
+		Mempool:      append([]string{}, c.mempool...),
+	}
+	for i, id := range c.orderedAccountIDs {
+		acc := c.accounts[id]
+		addrs := addresses[id]
+		if addrs == nil {
+			addrs = []string{}
+		}
+		sort.Strings(addrs)
+		pl.Accounts[i] = StateAccount{
+			Account:   newAccount(acc),
+			Label:     labels[id],
+			Addresses: addrs,
+		}
+	}
+	for i, id := range c.orderedTransactionIDs {
+		tx := c.withConfirmations(c.transactions[id])
+		pl.Transactions[i] = newTransaction(tx)
+	}
+	for url := range c.hooks {
+		pl.Hooks = append(pl.Hooks, url)
+	}
+	sort.Strings(pl.Hooks)
+	for i, b := range c.blocks {
+		pl.Blocks[i] = newBlock(b)
+	}
+	return pl
+}
+
+// State returns every account, transaction, hook and block of network.
+func (s *Service) State(network Network) State {
+	return s.chain(network).State()
+}
+
+// Reset returns network to the state it was in when the service was created.
+func (s *Service) Reset(network Network) {
+	s.chain(network).Reset()
+}
+
+// Snapshot saves the state of network and returns an ID that can be passed to
+// Restore.
+func (s *Service) Snapshot(network Network) int64 {
+	return s.chain(network).Snapshot()
+}
+
+// Restore returns network to the state saved by Snapshot.
+func (s *Service) Restore(network Network, id int64) error {
+	return s.chain(network).Restore(id)
+}
+
+// Now returns the time of network.
+func (s *Service) Now(network Network) time.Time {
+	return s.chain(network).Now()
+}
+
+// Advance moves the time of network forward by d, for example to expire
+// transaction IDs.
+func (s *Service) Advance(network Network, d time.Duration) {
+	s.chain(network).Advance(d)
+}
+
+func (c *chain) postResetHandler(w http.ResponseWriter, r *http.Request) {
+	c.Reset()
+}
+
+func (c *chain) getStateHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "state", "", []State{c.State()})
+}
+
+type snapshotPayload struct {
+	ID int64 `json:"id"`
+}
+
+func (c *chain) postSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusCreated, "snapshots", "",
+		[]snapshotPayload{
+			{ID: c.Snapshot()},
+		})
+}
+
+func (c *chain) postRestoreHandler(w http.ResponseWriter, r *http.Request) {
+
+	idValue := mux.Vars(r)["snapshot-id"]
+	id, err := strconv.ParseInt(idValue, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.Restore(id); err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+}
+
+func (c *chain) deleteSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+
+	idValue := mux.Vars(r)["snapshot-id"]
+	id, err := strconv.ParseInt(idValue, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !c.DeleteSnapshot(id) {
+		sendError(w, http.StatusNotFound, errSnapshotNotFound)
+		return
+	}
+}
+
+type timePayload struct {
+	Time time.Time `json:"time"`
+}
+
+func (c *chain) getTimeHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "time", "", []timePayload{{c.Now()}})
+}
+
+type setTimePayload struct {
+	Time    *time.Time `json:"time"`
+	Advance string     `json:"advance"`
+}
+
+// postTimeHandler moves the time of the chain to {"time": [RFC 3339 time]} or
+// forward by {"advance": [duration]}, for example "1h30m".
+func (c *chain) postTimeHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	pl := setTimePayload{}
+
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case pl.Time != nil && pl.Advance != "":
+		sendError(w, http.StatusBadRequest,
+			errors.New("only one of time and advance can be set"))
+		return
+	case pl.Time != nil:
+		c.SetTime(*pl.Time)
+	case pl.Advance != "":
+		d, err := time.ParseDuration(pl.Advance)
+		if err != nil || d < 0 {
+			sendError(w, http.StatusBadRequest, errors.New("invalid advance"))
+			return
+		}
+		c.Advance(d)
+	default:
+		sendError(w, http.StatusBadRequest,
+			errors.New("time or advance required"))
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "time", "", []timePayload{{c.Now()}})
+}
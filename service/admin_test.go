@@ -0,0 +1,154 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestAdminAuth(t *testing.T) {
+	s := service.New(service.AdminUserPass(service.MainNet, "root", "secret"))
+
+	w := serve(s, "GET", "/_mock/mainnet/state/", nil)
+	expectCode(t, w, http.StatusForbidden)
+
+	w = serveAdmin(s, "GET", "/_mock/mainnet/state/", nil)
+	expectCode(t, w, http.StatusUnauthorized)
+
+	w = serveAs(s, "root", "secret", "GET", "/_mock/mainnet/state/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	// Mock only endpoints are not found with the production endpoints.
+	w = serve(s, "POST", "/v1/mainnet/blocks/", struct {
+		N int `json:"n"`
+	}{1})
+	expectCode(t, w, http.StatusNotFound)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	s := service.New()
+
+	accID := fundedAccount(t, s, 1000)
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/snapshots/", nil)
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	url := fmt.Sprintf("/_mock/mainnet/snapshots/%d/restore", res.Payload[0].ID)
+
+	toAccID := createAccount(t, s)
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}{createTxID(t, s), accID, toAccID, 400})
+	expectCode(t, w, http.StatusCreated)
+
+	// Snapshots can be restored more than once.
+	for i := 0; i < 2; i++ {
+		w = serveAdmin(s, "POST", url, nil)
+		expectCode(t, w, http.StatusOK)
+
+		if b := balance(t, s, accID); b != 1000 {
+			t.Fatalf("expected balance 1000 got %d", b)
+		}
+		w = serve(s, "GET", fmt.Sprintf("/v1/mainnet/accounts/%d", toAccID), nil)
+		expectCode(t, w, http.StatusNotFound)
+
+		credit(t, s, createAddress(t, s, accID), 1)
+	}
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/reset/", nil)
+	expectCode(t, w, http.StatusOK)
+	w = serve(s, "GET", fmt.Sprintf("/v1/mainnet/accounts/%d", accID), nil)
+	expectCode(t, w, http.StatusNotFound)
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/snapshots/0/restore", nil)
+	expectCode(t, w, http.StatusNotFound)
+}
+
+func TestAdvanceTime(t *testing.T) {
+	s := service.New(service.TxIDExpiry(service.MainNet, time.Hour))
+
+	txID := createTxID(t, s)
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/time/", struct {
+		Advance string `json:"advance"`
+	}{"2h"})
+	expectCode(t, w, http.StatusOK)
+
+	if d := s.Now(service.MainNet).Sub(time.Now()); d < 2*time.Hour-time.Minute {
+		t.Fatalf("expected time 2h ahead got %v", d)
+	}
+
+	w = serve(s, "GET", fmt.Sprintf("/v1/mainnet/transactions/%d", txID), nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Status string
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Payload[0].Status != "expired" {
+		t.Fatalf("expected expired got %s", res.Payload[0].Status)
+	}
+}
+
+func TestState(t *testing.T) {
+	s := service.New()
+
+	accID := createAccount(t, s)
+	addr := createAddress(t, s, accID)
+	credit(t, s, addr, 500)
+
+	w := serveAdmin(s, "GET", "/_mock/mainnet/state/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Accounts []struct {
+				ID        int64
+				Balance   int64
+				Label     string
+				Addresses []string
+			}
+			Transactions []struct {
+				ID int64
+			}
+			Blocks []struct {
+				Height int64
+			}
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	state := res.Payload[0]
+	if len(state.Accounts) != 2 || state.Accounts[0].Label != "_fee" {
+		t.Fatalf("unexpected accounts %+v", state.Accounts)
+	}
+	acc := state.Accounts[1]
+	if acc.ID != accID || acc.Balance != 500 ||
+		len(acc.Addresses) != 1 || acc.Addresses[0] != addr {
+		t.Fatalf("unexpected account %+v", acc)
+	}
+	if len(state.Transactions) != 1 || len(state.Blocks) != 1 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
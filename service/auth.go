@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Role is the set of endpoints a Credential can use.
+type Role string
+
+const (
+	// ReadOnly credentials can only use GET endpoints, so can not create
+	// accounts or move funds.
+	ReadOnly Role = "read-only"
+
+	// Full credentials can use every endpoint of the production service.
+	Full Role = "full"
+
+	// Admin credentials can also use the /_mock/[network]/ endpoints.
+	Admin Role = "admin"
+)
+
+// Credential authenticates requests either with basic authentication, using
+// User and Pass, or with Token sent as "Authorization: Bearer [token]" or
+// "X-API-Key: [token]".
+type Credential struct {
+	User  string `json:"user,omitempty"`
+	Pass  string `json:"pass,omitempty"`
+	Token string `json:"token,omitempty"`
+
+	// Role defaults to Full.
+	Role Role `json:"role"`
+
+	// AccountIDs, if not empty, are the only accounts the credential can
+	// read or send funds from. Scoped credentials can not create accounts.
+	AccountIDs []int64 `json:"accountIDs,omitempty"`
+}
+
+func (cred Credential) validate() error {
+	if cred.User == "" && cred.Token == "" {
+		return errors.New("user or token required")
+	}
+	switch cred.Role {
+	case ReadOnly, Full, Admin:
+	default:
+		return errors.New("invalid role")
+	}
+	return nil
+}
+
+// allowsAccount reports whether accID is within the scope of the credential.
+func (cred Credential) allowsAccount(accID int64) bool {
+	if len(cred.AccountIDs) == 0 {
+		return true
+	}
+	for _, id := range cred.AccountIDs {
+		if id == accID {
+			return true
+		}
+	}
+	return false
+}
+
+func (cred Credential) scoped() bool {
+	return len(cred.AccountIDs) > 0
+}
+
+// AddCredential adds cred to the credentials of the chain, for example to
+// scope it to accounts that have been created.
+func (c *chain) AddCredential(cred Credential) error {
+	if cred.Role == "" {
+		cred.Role = Full
+	}
+	if err := cred.validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.credentials = append(c.credentials, cred)
+	return nil
+}
+
+func (c *chain) Credentials() []Credential {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]Credential{}, c.credentials...)
+}
+
+// requestToken returns the token r is authenticated with, if any.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth,
+		"Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// namedCredential returns the credential added to the chain, by the
+// Credentials option or AddCredential, that authenticates r.
+func (c *chain) namedCredential(r *http.Request) (Credential, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if token := requestToken(r); token != "" {
+		for _, cred := range c.credentials {
+			if cred.Token != "" && cred.Token == token {
+				return cred, true
+			}
+		}
+		return Credential{}, false
+	}
+
+	user, pass, exists := r.BasicAuth()
+	if !exists {
+		return Credential{}, false
+	}
+	for _, cred := range c.credentials {
+		if cred.User != "" && cred.User == user && cred.Pass == pass {
+			return cred, true
+		}
+	}
+	return Credential{}, false
+}
+
+// credential returns the credential that authenticates r.
+func (c *chain) credential(r *http.Request) (Credential, bool) {
+	if cred, exists := c.namedCredential(r); exists {
+		return cred, true
+	}
+	if requestToken(r) != "" {
+		return Credential{}, false
+	}
+
+	user, pass, exists := r.BasicAuth()
+	if !exists {
+		return Credential{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch {
+	case user == c.adminUser && pass == c.adminPass:
+		return Credential{User: user, Pass: pass, Role: Admin}, true
+	case pass == c.pass && (user == c.user || c.multiTenant):
+		// Tenants are selected by user name so any is accepted.
+		return Credential{User: user, Pass: pass, Role: Full}, true
+	}
+	return Credential{}, false
+}
+
+type credentialKey struct{}
+
+// requestCredential returns the credential that authenticated r.
+func requestCredential(r *http.Request) Credential {
+	cred, _ := r.Context().Value(credentialKey{}).(Credential)
+	return cred
+}
+
+// allowsAccount reports whether the credential that authenticated r can use
+// the account accID.
+func allowsAccount(r *http.Request, accID int64) bool {
+	return requestCredential(r).allowsAccount(accID)
+}
+
+// authMiddleware only allows requests authenticated by a credential with at
+// least the role required by the endpoint. Read-only credentials are
+// restricted to GET requests.
+func (c *chain) authMiddleware(required Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cred, exists := c.credential(r)
+			if !exists {
+				sendError(w, http.StatusUnauthorized, errUnauthenticated)
+				return
+			}
+
+			if (required == Admin && cred.Role != Admin) ||
+				(cred.Role == ReadOnly && r.Method != "GET") {
+				sendError(w, http.StatusForbidden, errRoleForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), credentialKey{}, cred)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Credentials is an option that can be passed to New() to add credentials to
+// those set by UserPass and AdminUserPass for the specified network. It
+// panics if a credential is invalid. Credentials can also be added while the
+// service is running using AddCredential or the
+// /_mock/[network]/credentials/ endpoint.
+func Credentials(network Network, creds ...Credential) Option {
+	return func(c *chain) {
+		if c.network != network {
+			return
+		}
+		for _, cred := range creds {
+			if err := c.AddCredential(cred); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// AddCredential adds cred to the credentials of network. Its Role defaults to
+// Full.
+func (s *Service) AddCredential(network Network, cred Credential) error {
+	return s.chain(network).AddCredential(cred)
+}
+
+func (c *chain) getCredentialsHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "credentials", "", c.Credentials())
+}
+
+func (c *chain) postCredentialsHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	cred := Credential{}
+	if err := c.decodeJSON(r.Body, &cred); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.AddCredential(cred); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+type transferPayload struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"fromAccountID"`
+	ToAccountID   int64 `json:"toAccountID"`
+	Value         int64 `json:"value"`
+}
+
+func TestReadOnlyCredential(t *testing.T) {
+	s := service.New(service.Credentials(service.MainNet, service.Credential{
+		User: "reports",
+		Pass: "secret",
+		Role: service.ReadOnly,
+	}))
+
+	fromAccID := fundedAccount(t, s, 1000)
+	toAccID := createAccount(t, s)
+
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d", fromAccID)
+	w := serveAs(s, "reports", "secret", "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	w = serveAs(s, "reports", "secret", "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), fromAccID, toAccID, 100})
+	expectCode(t, w, http.StatusForbidden)
+
+	w = serveAs(s, "reports", "secret", "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusForbidden)
+
+	if b := balance(t, s, fromAccID); b != 1000 {
+		t.Fatalf("expected balance 1000 got %d", b)
+	}
+}
+
+func TestTokenCredential(t *testing.T) {
+	s := service.New(service.Credentials(service.MainNet, service.Credential{
+		Token: "key",
+		Role:  service.Admin,
+	}))
+
+	for _, header := range []string{"Authorization", "X-API-Key"} {
+		value := "key"
+		if header == "Authorization" {
+			value = "Bearer key"
+		}
+
+		for url, code := range map[string]int{
+			"/v1/mainnet/fees/":     http.StatusOK,
+			"/_mock/mainnet/state/": http.StatusOK,
+		} {
+			r := httptest.NewRequest("GET", url, nil)
+			r.Header.Set("Accept", "application/json")
+			r.Header.Set(header, value)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			expectCode(t, w, code)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/v1/mainnet/fees/", nil)
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusUnauthorized)
+}
+
+func TestScopedCredential(t *testing.T) {
+	s := service.New()
+
+	accID := fundedAccount(t, s, 1000)
+	otherAccID := fundedAccount(t, s, 1000)
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/credentials/",
+		service.Credential{
+			User:       "scoped",
+			Pass:       "secret",
+			AccountIDs: []int64{accID},
+		})
+	expectCode(t, w, http.StatusCreated)
+
+	w = serveAs(s, "scoped", "secret", "GET", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != 1 || res.Payload[0].ID != accID {
+		t.Fatalf("expected only account %d got %+v", accID, res.Payload)
+	}
+
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d", otherAccID)
+	w = serveAs(s, "scoped", "secret", "GET", url, nil)
+	expectCode(t, w, http.StatusForbidden)
+
+	// Funds can be sent to, but not from, other accounts.
+	w = serveAs(s, "scoped", "secret", "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), otherAccID, accID, 100})
+	expectCode(t, w, http.StatusForbidden)
+
+	w = serveAs(s, "scoped", "secret", "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), accID, otherAccID, 100})
+	expectCode(t, w, http.StatusCreated)
+
+	if b := balance(t, s, otherAccID); b != 1100 {
+		t.Fatalf("expected balance 1100 got %d", b)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"net/http"
+)
+
+const maxMineBlocks = 100
+
+// Block is a block of the simulated chain.
+type Block struct {
+	Height   int64    `json:"height"`
+	Hash     string   `json:"hash"`
+	TxHashes []string `json:"txHashes"`
+}
+
+func newBlock(b block) Block {
+	txHashes := b.txHashes
+	if txHashes == nil {
+		txHashes = []string{}
+	}
+	return Block{
+		Height:   b.height,
+		Hash:     b.hash,
+		TxHashes: txHashes,
+	}
+}
+
+// postBlocksHandler mines blocks confirming the transactions in the mempool.
+func (c *chain) postBlocksHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	n := countPayload{}
+
+	if err := c.decodeJSON(r.Body, &n); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if n.N < 1 || n.N > maxMineBlocks {
+		sendError(w, http.StatusBadRequest,
+			newError("invalid_n", "n must be > 0 and <= 100"))
+		return
+	}
+
+	blocks := c.Mine(n.N)
+
+	payload := make([]Block, len(blocks))
+	for i, b := range blocks {
+		payload[i] = newBlock(b)
+	}
+	sendPayload(w, http.StatusCreated, "blocks", "", payload)
+}
+
+type reorgPayload struct {
+	Depth int `json:"depth"`
+}
+
+// postReorgsHandler removes the most recent blocks from the chain, reversing
+// the credits they confirmed.
+func (c *chain) postReorgsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	pl := reorgPayload{}
+
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	txIDs, err := c.Reorg(pl.Depth)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	c.sendTransactions(w, txIDs)
+}
+
+type doubleSpendPayload struct {
+	ID     int64  `json:"id"`
+	TxHash string `json:"txHash"`
+}
+
+// postDoubleSpendsHandler permanently reverses the credits of an on-chain
+// transaction identified by its hash or by the ID of one of its credits.
+func (c *chain) postDoubleSpendsHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	pl := doubleSpendPayload{}
+
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if pl.TxHash == "" {
+		tx, exists := c.Transaction(pl.ID)
+		if !exists || tx.ty != "credit" {
+			sendError(w, http.StatusNotFound, errCreditNotFound)
+			return
+		}
+		pl.TxHash = tx.txHash
+	}
+
+	txIDs, err := c.DoubleSpend(pl.TxHash)
+	if err == errCreditNotFound {
+		sendError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	c.sendTransactions(w, txIDs)
+}
+
+// sendTransactions responds with the transactions txIDs.
+func (c *chain) sendTransactions(w http.ResponseWriter, txIDs []int64) {
+	payload := make([]Transaction, len(txIDs))
+	for i, txID := range txIDs {
+		tx, _ := c.Transaction(txID)
+		payload[i] = newTransaction(tx)
+	}
+	sendPayload(w, http.StatusOK, "transactions", "", payload)
+}
+
+// Mine mines n blocks on network, the first of which confirms every
+// transaction in the mempool, and returns them.
+func (s *Service) Mine(network Network, n int) []Block {
+	if n < 1 {
+		return []Block{}
+	}
+	blocks := s.chain(network).Mine(n)
+	pl := make([]Block, len(blocks))
+	for i, b := range blocks {
+		pl[i] = newBlock(b)
+	}
+	return pl
+}
+
+// Reorg removes the last depth blocks of network. Their credits are reversed
+// until they are mined again.
+func (s *Service) Reorg(network Network, depth int) error {
+	_, err := s.chain(network).Reorg(depth)
+	return err
+}
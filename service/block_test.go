@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestReorg(t *testing.T) {
+	s := service.New()
+
+	accID := fundedAccount(t, s, 1000)
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/reorgs/", struct {
+		Depth int `json:"depth"`
+	}{
+		Depth: 1,
+	})
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Status string
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != 1 || res.Payload[0].Status != "unconfirmed" {
+		t.Fatalf("unexpected credits %+v", res.Payload)
+	}
+	if b := balance(t, s, accID); b != 0 {
+		t.Fatalf("expected balance 0 got %d", b)
+	}
+
+	// Mining the reorganised transaction confirms the credit again.
+	w = serveAdmin(s, "POST", "/_mock/mainnet/blocks/", struct {
+		N int `json:"n"`
+	}{
+		N: 1,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	if b := balance(t, s, accID); b != 1000 {
+		t.Fatalf("expected balance 1000 got %d", b)
+	}
+}
+
+func TestDoubleSpend(t *testing.T) {
+	s := service.New()
+
+	accID := createAccount(t, s)
+	creditTxID := credit(t, s, createAddress(t, s, accID), 1000)
+	toAccID := createAccount(t, s)
+
+	type transfer struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", transfer{
+		ID:            createTxID(t, s),
+		FromAccountID: accID,
+		ToAccountID:   toAccID,
+		Value:         600,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/doublespends/", struct {
+		ID int64 `json:"id"`
+	}{
+		ID: creditTxID,
+	})
+	expectCode(t, w, http.StatusOK)
+
+	if b := balance(t, s, accID); b != -600 {
+		t.Fatalf("expected balance -600 got %d", b)
+	}
+
+	// The account is frozen until its balance is restored.
+	addr := createAddress(t, s, accID)
+	for _, c := range []struct {
+		credit int64
+		code   int
+	}{
+		{500, http.StatusBadRequest},
+		{200, http.StatusCreated},
+	} {
+		credit(t, s, addr, c.credit)
+
+		w = serve(s, "PUT", "/v1/mainnet/transactions/", transfer{
+			ID:            createTxID(t, s),
+			FromAccountID: accID,
+			ToAccountID:   toAccID,
+			Value:         50,
+		})
+		expectCode(t, w, c.code)
+	}
+
+	// Double spending the same transaction twice has no further effect.
+	w = serveAdmin(s, "POST", "/_mock/mainnet/doublespends/", struct {
+		ID int64 `json:"id"`
+	}{
+		ID: creditTxID,
+	})
+	expectCode(t, w, http.StatusOK)
+
+	if b := balance(t, s, accID); b != 50 {
+		t.Fatalf("expected balance 50 got %d", b)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// apiError is an error sent to clients with a code that identifies it. Codes
+// are stable and listed in README.md, whereas messages may change.
+type apiError struct {
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func newError(code, message string) *apiError {
+	return &apiError{code: code, message: message}
+}
+
+// errorf returns an error with code and a formatted message.
+func errorf(code, format string, args ...interface{}) *apiError {
+	return newError(code, fmt.Sprintf(format, args...))
+}
+
+// Errors shared by several endpoints. Those specific to one endpoint are
+// declared beside it.
+var (
+	errInvalidJSON      = newError("invalid_json", "invalid json")
+	errUnauthenticated  = newError("unauthenticated", "user pass unauthenticated")
+	errRoleForbidden    = newError("role_not_permitted", "role not permitted")
+	errAccountForbidden = newError("account_not_permitted",
+		"account not permitted")
+	errRouteNotFound    = newError("route_not_found", "route not found")
+	errMethodNotAllowed = newError("method_not_allowed", "method not allowed")
+)
+
+// errorCode returns the code of err, which is derived from the status of the
+// response if err does not have one.
+func errorCode(status int, err error) string {
+	if e, ok := err.(*apiError); ok {
+		return e.code
+	}
+	switch status {
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusInternalServerError:
+		return "internal_error"
+	}
+	return "invalid_request"
+}
+
+func newErrorPayload(status int, err error) errorPayload {
+	return errorPayload{
+		Code:    errorCode(status, err),
+		Message: err.Error(),
+	}
+}
+
+func routeNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	sendError(w, http.StatusNotFound, errRouteNotFound)
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	sendError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+}
@@ -0,0 +1,116 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func expectErrorCode(t *testing.T, w *httptest.ResponseRecorder,
+	status int, code string) {
+
+	expectCode(t, w, status)
+
+	res := struct {
+		Type    string
+		Payload []struct {
+			Code    string
+			Message string
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != "errors" || len(res.Payload) != 1 ||
+		res.Payload[0].Code != code || res.Payload[0].Message == "" {
+		t.Fatalf("expected error code %s got %+v", code, res)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	s := service.New()
+
+	w := serveAs(s, "user", "wrong", "GET", "/v1/mainnet/fees/", nil)
+	expectErrorCode(t, w, http.StatusUnauthorized, "unauthenticated")
+
+	r := httptest.NewRequest("GET", "/v1/mainnet/fees/", nil)
+	r.SetBasicAuth("user", "pass")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectErrorCode(t, w, http.StatusBadRequest, "header_not_found")
+
+	w = serve(s, "GET", "/v1/mainnet/accounts/1", nil)
+	expectErrorCode(t, w, http.StatusNotFound, "account_not_found")
+
+	w = serve(s, "GET", "/v1/mainnet/unknown/", nil)
+	expectErrorCode(t, w, http.StatusNotFound, "route_not_found")
+
+	w = serve(s, "GET", "/v1/mainnet/accounts/0/transactions/", nil)
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_account_id")
+
+	w = serve(s, "GET", "/v1/mainnet/accounts/?limit=-1", nil)
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_limit")
+
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d/transactions/?next=-1",
+		createAccount(t, s))
+	w = serve(s, "GET", url, nil)
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_next")
+
+	w = serve(s, "GET", "/v1/regtest/fees/", nil)
+	expectErrorCode(t, w, http.StatusNotFound, "route_not_found")
+
+	w = serve(s, "DELETE", "/v1/mainnet/fees/", nil)
+	expectErrorCode(t, w, http.StatusMethodNotAllowed, "method_not_allowed")
+
+	w = serve(s, "POST", "/_mock/mainnet/tenants/", nil)
+	expectErrorCode(t, w, http.StatusMethodNotAllowed, "method_not_allowed")
+
+	w = serve(s, "POST", "/v1/mainnet/hooks/", struct {
+		URL string `json:"url"`
+	}{"ftp://example.com"})
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_url")
+
+	fromAccID := fundedAccount(t, s, 100)
+	toAccID := createAccount(t, s)
+	w = serve(s, "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), fromAccID, toAccID, 200})
+	expectErrorCode(t, w, http.StatusBadRequest, "insufficient_funds")
+
+	w = serve(s, "PUT", "/v1/mainnet/transactions/",
+		transferPayload{1, fromAccID, toAccID, 50})
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_tx_id")
+
+	w = serve(s, "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), 0, toAccID, 50})
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_account_id")
+
+	w = serve(s, "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), fromAccID, toAccID, 0})
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_value")
+
+	type debitPayload struct {
+		ID            int64  `json:"id"`
+		FromAccountID int64  `json:"fromAccountID"`
+		ToAddress     string `json:"toAddress"`
+		Value         int64  `json:"value"`
+	}
+	for _, addr := range []string{
+		"xyz",
+		// Testnet address.
+		"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+	} {
+		w = serve(s, "PUT", "/v1/mainnet/transactions/",
+			debitPayload{createTxID(t, s), fromAccID, addr, 10})
+		expectErrorCode(t, w, http.StatusBadRequest, "invalid_address")
+	}
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/addresses/"+
+		createAddress(t, s, toAccID), struct {
+		RawTx string `json:"rawTx"`
+	}{"00"})
+	expectErrorCode(t, w, http.StatusBadRequest, "field_not_allowed")
+}
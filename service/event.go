@@ -0,0 +1,93 @@
+package service
+
+// Event types.
+const (
+	// AccountEvent is sent when an account is created.
+	AccountEvent = "account"
+
+	// TransactionEvent is sent when a transaction is created or its status
+	// changes, for example when a credit is confirmed or reversed.
+	TransactionEvent = "transaction"
+
+	// BlockEvent is sent when a block is mined.
+	BlockEvent = "block"
+)
+
+// eventBuffer is the number of events held for a subscriber before further
+// events are dropped.
+const eventBuffer = 1024
+
+// Event is a change to the state of a network. Only the field given by Type
+// is set.
+type Event struct {
+	Type string
+
+	Account     *Account
+	Transaction *Transaction
+	Block       *Block
+}
+
+// subscribe returns a channel receiving the events of the chain and a
+// function that closes it.
+func (c *chain) subscribe() (<-chan Event, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan Event]struct{})
+	}
+	ch := make(chan Event, eventBuffer)
+	c.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if _, exists := c.subscribers[ch]; exists {
+			delete(c.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// emit sends e to every subscriber without blocking. c.mu must be held.
+func (c *chain) emit(e Event) {
+	for ch := range c.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (c *chain) emitAccount(accID int64) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	acc := newAccount(c.accounts[accID])
+	c.emit(Event{Type: AccountEvent, Account: &acc})
+}
+
+func (c *chain) emitTransaction(txID int64) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	tx := newTransaction(c.withConfirmations(c.transactions[txID]))
+	c.emit(Event{Type: TransactionEvent, Transaction: &tx})
+}
+
+func (c *chain) emitBlock(b block) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	pl := newBlock(b)
+	c.emit(Event{Type: BlockEvent, Block: &pl})
+}
+
+// Subscribe returns a channel receiving the events of network and a function
+// that stops them and closes the channel. Events are dropped if more than
+// 1024 are waiting to be received.
+func (s *Service) Subscribe(network Network) (<-chan Event, func()) {
+	return s.chain(network).subscribe()
+}
@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestSubscribe(t *testing.T) {
+	s := service.New()
+
+	events, cancel := s.Subscribe(service.MainNet)
+
+	acc := s.CreateAccount(service.MainNet, 0)
+	if e := <-events; e.Type != service.AccountEvent || e.Account.ID != acc.ID {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	addr, err := s.CreateAddress(service.MainNet, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := s.Credit(service.MainNet, addr, 500, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Type != service.TransactionEvent ||
+		e.Transaction.ID != tx.ID || e.Transaction.Status != "unconfirmed" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	blocks := s.Mine(service.MainNet, 1)
+	if e := <-events; e.Type != service.BlockEvent ||
+		e.Block.Hash != blocks[0].Hash {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if e := <-events; e.Type != service.TransactionEvent ||
+		e.Transaction.Status != "complete" ||
+		e.Transaction.Confirmations != 1 {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	if err := s.Reorg(service.MainNet, 1); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Type != service.TransactionEvent ||
+		e.Transaction.Status != "unconfirmed" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("expected events to be closed")
+	}
+	cancel()
+}
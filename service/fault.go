@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// FaultRule injects a fault into requests to a network that match its Method
+// and Path. Faults allow clients' retry and error handling logic to be
+// tested.
+type FaultRule struct {
+	// Method is the HTTP method to match. Empty matches every method.
+	Method string
+
+	// Path is a pattern, as used by path.Match, matched against the request
+	// path after /v1/[network]. For example "/transactions/" or
+	// "/accounts/*". Empty matches every path.
+	Path string
+
+	// Probability is the chance, between 0 and 1, that a matching request is
+	// faulted. Zero faults every matching request.
+	Probability float64
+
+	// Nth only faults the nth matching request, counting from 1. Zero
+	// faults every matching request.
+	Nth int
+
+	// Latency delays the response.
+	Latency time.Duration
+
+	// Status responds with the status code and an error instead of calling
+	// the endpoint.
+	Status int
+
+	// Drop closes the connection without responding.
+	Drop bool
+
+	// MalformedJSON responds with a truncated JSON body instead of calling
+	// the endpoint.
+	MalformedJSON bool
+}
+
+type faultRulePayload struct {
+	Method        string  `json:"method"`
+	Path          string  `json:"path"`
+	Probability   float64 `json:"probability"`
+	Nth           int     `json:"nth"`
+	Latency       string  `json:"latency"`
+	Status        int     `json:"status"`
+	Drop          bool    `json:"drop"`
+	MalformedJSON bool    `json:"malformedJSON"`
+}
+
+// MarshalJSON encodes the rule with its latency as a duration string such as
+// "250ms".
+func (f FaultRule) MarshalJSON() ([]byte, error) {
+	pl := faultRulePayload{
+		Method:        f.Method,
+		Path:          f.Path,
+		Probability:   f.Probability,
+		Nth:           f.Nth,
+		Status:        f.Status,
+		Drop:          f.Drop,
+		MalformedJSON: f.MalformedJSON,
+	}
+	if f.Latency > 0 {
+		pl.Latency = f.Latency.String()
+	}
+	return json.Marshal(pl)
+}
+
+// UnmarshalJSON decodes a rule encoded by MarshalJSON.
+func (f *FaultRule) UnmarshalJSON(b []byte) error {
+	pl := faultRulePayload{}
+	if err := json.Unmarshal(b, &pl); err != nil {
+		return err
+	}
+
+	latency := time.Duration(0)
+	if pl.Latency != "" {
+		var err error
+		if latency, err = time.ParseDuration(pl.Latency); err != nil {
+			return errors.New("invalid latency")
+		}
+	}
+
+	*f = FaultRule{
+		Method:        pl.Method,
+		Path:          pl.Path,
+		Probability:   pl.Probability,
+		Nth:           pl.Nth,
+		Latency:       latency,
+		Status:        pl.Status,
+		Drop:          pl.Drop,
+		MalformedJSON: pl.MalformedJSON,
+	}
+	return nil
+}
+
+func (f FaultRule) validate() error {
+	if f.Probability < 0 || f.Probability > 1 {
+		return errors.New("probability must be >= 0 and <= 1")
+	}
+	if f.Nth < 0 {
+		return errors.New("nth must be >= 0")
+	}
+	if f.Latency < 0 {
+		return errors.New("latency must be >= 0")
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 599) {
+		return errors.New("invalid status")
+	}
+	if _, err := path.Match(f.Path, ""); err != nil {
+		return errors.New("invalid path")
+	}
+	return nil
+}
+
+var errInjectedFault = newError("injected_fault", "injected fault")
+
+// fault is a FaultRule and the number of requests it has matched.
+type fault struct {
+	rule  FaultRule
+	calls int
+}
+
+// SetFaults replaces the chain's fault rules.
+func (c *chain) SetFaults(rules []FaultRule) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.faults = make([]*fault, len(rules))
+	for i, rule := range rules {
+		c.faults[i] = &fault{rule: rule}
+	}
+	return nil
+}
+
+func (c *chain) Faults() []FaultRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rules := make([]FaultRule, len(c.faults))
+	for i, f := range c.faults {
+		rules[i] = f.rule
+	}
+	return rules
+}
+
+// fault returns the first rule that faults a request with method and urlPath.
+func (c *chain) fault(method, urlPath string) (FaultRule, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, f := range c.faults {
+		if !requestMatches(f.rule.Method, f.rule.Path, "", method, urlPath) {
+			continue
+		}
+		f.calls++
+		if f.rule.Nth > 0 && f.calls != f.rule.Nth {
+			continue
+		}
+		if f.rule.Probability > 0 && rand.Float64() >= f.rule.Probability {
+			continue
+		}
+		return f.rule, true
+	}
+	return FaultRule{}, false
+}
+
+// faultMiddleware injects the faults described by the chain's fault rules
+// into requests to endpoints under prefix.
+func (c *chain) faultMiddleware(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			urlPath := strings.TrimPrefix(r.URL.Path, prefix)
+			rule, exists := c.fault(r.Method, urlPath)
+			if !exists {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			time.Sleep(rule.Latency)
+
+			switch {
+			case rule.Drop:
+				// Aborting the handler closes the connection.
+				panic(http.ErrAbortHandler)
+			case rule.MalformedJSON:
+				status := rule.Status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				w.Write([]byte(`{"type":"errors","payload":[{"mess`))
+			case rule.Status != 0:
+				sendError(w, rule.Status, errInjectedFault)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func (c *chain) getFaultsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "faults", "", c.Faults())
+}
+
+// putFaultsHandler replaces the fault rules with the JSON array of rules in
+// the request body.
+func (c *chain) putFaultsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	rules := []FaultRule{}
+	if err := c.decodeJSON(r.Body, &rules); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.SetFaults(rules); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (c *chain) deleteFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	c.SetFaults(nil)
+}
@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestFaultNth(t *testing.T) {
+	s := service.New(service.Faults(service.MainNet, service.FaultRule{
+		Method: "GET",
+		Path:   "/fees/",
+		Nth:    2,
+		Status: http.StatusServiceUnavailable,
+	}))
+
+	for _, code := range []int{
+		http.StatusOK,
+		http.StatusServiceUnavailable,
+		http.StatusOK,
+	} {
+		w := serve(s, "GET", "/v1/mainnet/fees/", nil)
+		expectCode(t, w, code)
+	}
+
+	// Other networks are unaffected.
+	r := httptest.NewRequest("GET", "/v1/testnet3/fees/", nil)
+	r.SetBasicAuth("user", "pass")
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusOK)
+}
+
+func TestFaultEndpoint(t *testing.T) {
+	s := service.New()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	w := serveAdmin(s, "PUT", "/_mock/mainnet/faults/", []service.FaultRule{
+		{
+			Method: "POST",
+			Path:   "/accounts/",
+			Drop:   true,
+		},
+		{
+			Path:          "/accounts/*",
+			Latency:       10 * time.Millisecond,
+			MalformedJSON: true,
+		},
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	req, err := http.NewRequest("POST", server.URL+"/v1/mainnet/accounts/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("user", "pass")
+	req.Header.Set("Accept", "application/json")
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("expected dropped connection")
+	}
+
+	w = serve(s, "GET", "/v1/mainnet/accounts/1", nil)
+	expectCode(t, w, http.StatusOK)
+	if err := json.NewDecoder(w.Body).Decode(&struct{}{}); err == nil {
+		t.Fatal("expected malformed json")
+	}
+
+	w = serveAdmin(s, "DELETE", "/_mock/mainnet/faults/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	w = serve(s, "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusCreated)
+}
+
+func TestFaultAuth(t *testing.T) {
+	s := service.New(service.Faults(service.MainNet, service.FaultRule{
+		Method: "GET",
+		Path:   "/fees/",
+		Status: http.StatusServiceUnavailable,
+	}))
+
+	// Faults only apply to requests that are authenticated.
+	w := serveAs(s, "user", "wrong", "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusUnauthorized)
+
+	w = serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusServiceUnavailable)
+}
@@ -0,0 +1,592 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fixtures describe the state of networks, keyed by network, loaded by the
+// Seed option. They are usually read from a YAML file, for example:
+//
+//	mainnet:
+//	  fees:
+//	    - feePerByte: 120
+//	      blockHeight: 451000
+//	  hooks:
+//	    - https://example.com/hook
+//	  accounts:
+//	    - label: alice
+//	      addresses: [1BoatSLRHtKNngkdXEeobR76b53LETtpyT]
+//	    - label: bob
+//	      balance: 250
+//	  transactions:
+//	    - type: credit
+//	      to: alice
+//	      value: 5000
+//	      created: 2020-01-02T15:04:05Z
+//	    - type: transfer
+//	      from: alice
+//	      to: bob
+//	      value: 1000
+type Fixtures map[Network]NetworkFixtures
+
+// NetworkFixtures describe the fee table, hooks, accounts and transactions of
+// a network.
+type NetworkFixtures struct {
+	Fees         []FeeFixture         `yaml:"fees,omitempty" json:"fees,omitempty"`
+	Hooks        []string             `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Accounts     []AccountFixture     `yaml:"accounts,omitempty" json:"accounts,omitempty"`
+	Transactions []TransactionFixture `yaml:"transactions,omitempty" json:"transactions,omitempty"`
+}
+
+// FeeFixture is an entry of the fee table returned by GET /fees/. The first
+// entry is charged for debits.
+type FeeFixture struct {
+	FeePerByte  int64 `yaml:"feePerByte" json:"feePerByte"`
+	BlockHeight int64 `yaml:"blockHeight" json:"blockHeight"`
+}
+
+// AccountFixture is an account, given a random ID if ID is 0. Its balance is
+// the total of its transactions unless Balance is set. Frozen accounts can not
+// send funds.
+type AccountFixture struct {
+	ID        int64    `yaml:"id,omitempty" json:"id,omitempty"`
+	Label     string   `yaml:"label,omitempty" json:"label,omitempty"`
+	Balance   *int64   `yaml:"balance,omitempty" json:"balance,omitempty"`
+	Frozen    bool     `yaml:"frozen,omitempty" json:"frozen,omitempty"`
+	Addresses []string `yaml:"addresses,omitempty" json:"addresses,omitempty"`
+}
+
+// TransactionFixture is a complete transfer, debit or credit. Its accounts are
+// given by their label, From and To, or ID, FromAccountID and ToAccountID.
+// Debits pay ToAddress or Outputs, in which case Value defaults to their total.
+// Credits can give the address of the account they paid as ToAddress and the
+// output of TxHash that paid it as Vout, which is unique for each TxHash.
+// Transactions are given a random ID if ID is 0 and the current time if
+// Created is not set.
+type TransactionFixture struct {
+	ID   int64  `yaml:"id,omitempty" json:"id,omitempty"`
+	Type string `yaml:"type" json:"type"`
+
+	From          string `yaml:"from,omitempty" json:"from,omitempty"`
+	FromAccountID int64  `yaml:"fromAccountID,omitempty" json:"fromAccountID,omitempty"`
+	To            string `yaml:"to,omitempty" json:"to,omitempty"`
+	ToAccountID   int64  `yaml:"toAccountID,omitempty" json:"toAccountID,omitempty"`
+
+	ToAddress string          `yaml:"toAddress,omitempty" json:"toAddress,omitempty"`
+	Outputs   []OutputFixture `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+
+	Value int64 `yaml:"value,omitempty" json:"value,omitempty"`
+	Fee   int64 `yaml:"fee,omitempty" json:"fee,omitempty"`
+
+	TxHash      string    `yaml:"txHash,omitempty" json:"txHash,omitempty"`
+	Vout        int64     `yaml:"vout,omitempty" json:"vout,omitempty"`
+	FromAddress string    `yaml:"fromAddress,omitempty" json:"fromAddress,omitempty"`
+	Created     time.Time `yaml:"created,omitempty" json:"created"`
+}
+
+// OutputFixture is a payment made by a debit.
+type OutputFixture struct {
+	ToAddress string `yaml:"toAddress" json:"toAddress"`
+	Value     int64  `yaml:"value" json:"value"`
+}
+
+// FixtureError lists the problems found in fixtures.
+type FixtureError struct {
+	Problems []string
+}
+
+func (e *FixtureError) Error() string {
+	return "invalid fixtures:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// ReadFixtures reads YAML fixtures from r and validates them.
+func ReadFixtures(r io.Reader) (Fixtures, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f := Fixtures{}
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixtures: %v", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write writes f to w as YAML that can be read by ReadFixtures.
+func (f Fixtures) Write(w io.Writer) error {
+	b, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Validate returns a *FixtureError listing every problem with f, or nil if it
+// can be loaded.
+func (f Fixtures) Validate() error {
+	networks := []string{}
+	for network := range f {
+		networks = append(networks, string(network))
+	}
+	sort.Strings(networks)
+
+	problems := []string{}
+	for _, network := range networks {
+		params, exists := networkParams[Network(network)]
+		if !exists {
+			problems = append(problems, fmt.Sprintf(
+				"%s: unknown network, expected %s or %s", network, MainNet,
+				TestNet3))
+			continue
+		}
+		v := fixtureValidator{
+			prefix:   network + ".",
+			params:   params,
+			ids:      make(map[int64]string),
+			labels:   make(map[string]int),
+			accounts: make(map[int64]int),
+			owners:   make(map[string]int),
+		}
+		v.validate(f[Network(network)])
+		problems = append(problems, v.problems...)
+	}
+	if len(problems) > 0 {
+		return &FixtureError{Problems: problems}
+	}
+	return nil
+}
+
+// fixtureValidator collects the problems of the fixtures of a network.
+type fixtureValidator struct {
+	prefix   string
+	params   *chaincfg.Params
+	problems []string
+
+	// ids maps the IDs of accounts and transactions to where they are
+	// declared. labels and accounts map account labels and IDs to their
+	// index and owners maps addresses to the index of their account.
+	ids      map[int64]string
+	labels   map[string]int
+	accounts map[int64]int
+	owners   map[string]int
+}
+
+func (v *fixtureValidator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, v.prefix+fmt.Sprintf(format, args...))
+}
+
+func (v *fixtureValidator) validate(f NetworkFixtures) {
+	for i, fee := range f.Fees {
+		if fee.FeePerByte <= 0 {
+			v.add("fees[%d].feePerByte: must be > 0", i)
+		}
+		if fee.BlockHeight < 0 {
+			v.add("fees[%d].blockHeight: must be >= 0", i)
+		}
+	}
+
+	if len(f.Hooks) > maxHooks {
+		v.add("hooks: at most %d hooks are allowed", maxHooks)
+	}
+	hooks := make(map[string]bool)
+	for i, url := range f.Hooks {
+		if err := checkHookURL(url); err != nil {
+			v.add("hooks[%d]: %v %q", i, err, url)
+		} else if hooks[url] {
+			v.add("hooks[%d]: %q is repeated", i, url)
+		}
+		hooks[url] = true
+	}
+
+	for i, acc := range f.Accounts {
+		v.validateAccount(fmt.Sprintf("accounts[%d]", i), i, acc)
+	}
+	for i, tx := range f.Transactions {
+		v.validateTransaction(fmt.Sprintf("transactions[%d]", i), tx)
+	}
+}
+
+// validateID checks the ID of the account or transaction at path.
+func (v *fixtureValidator) validateID(path string, id int64) {
+	switch {
+	case id < 0:
+		v.add("%s.id: must be > 0", path)
+	case id > 0:
+		if other, exists := v.ids[id]; exists {
+			v.add("%s.id: %d is already used by %s", path, id, other)
+			return
+		}
+		v.ids[id] = path
+	}
+}
+
+// validateAddress checks the address at path is an address of the network.
+func (v *fixtureValidator) validateAddress(path, address string) bool {
+	addr, err := btcutil.DecodeAddress(address, v.params)
+	if err != nil {
+		v.add("%s: invalid address %q", path, address)
+		return false
+	}
+	if !addr.IsForNet(v.params) {
+		v.add("%s: %q is not a %s address", path, address, v.params.Name)
+		return false
+	}
+	return true
+}
+
+func (v *fixtureValidator) validateAccount(path string, i int,
+	acc AccountFixture) {
+
+	v.validateID(path, acc.ID)
+	if acc.ID > 0 {
+		v.accounts[acc.ID] = i
+	}
+
+	if acc.Label != "" {
+		if j, exists := v.labels[acc.Label]; exists {
+			v.add("%s.label: %q is already used by accounts[%d]", path,
+				acc.Label, j)
+		}
+		v.labels[acc.Label] = i
+	}
+
+	for j, addr := range acc.Addresses {
+		addrPath := fmt.Sprintf("%s.addresses[%d]", path, j)
+		if !v.validateAddress(addrPath, addr) {
+			continue
+		}
+		if k, exists := v.owners[addr]; exists {
+			v.add("%s: %q is already used by accounts[%d]", addrPath, addr, k)
+		}
+		v.owners[addr] = i
+	}
+}
+
+// account returns the index of the account of a transaction given by label or
+// id, which are the fields name and nameAccountID.
+func (v *fixtureValidator) account(path, name, label string,
+	id int64) (int, bool) {
+
+	switch {
+	case label != "" && id != 0:
+		v.add("%s: only one of %s and %sAccountID can be set", path, name,
+			name)
+	case label != "":
+		i, exists := v.labels[label]
+		if !exists {
+			v.add("%s.%s: no account is labelled %q", path, name, label)
+		}
+		return i, exists
+	case id != 0:
+		i, exists := v.accounts[id]
+		if !exists {
+			v.add("%s.%sAccountID: no account has ID %d", path, name, id)
+		}
+		return i, exists
+	default:
+		v.add("%s: %s or %sAccountID is required", path, name, name)
+	}
+	return 0, false
+}
+
+func (v *fixtureValidator) validateTransaction(path string,
+	tx TransactionFixture) {
+
+	v.validateID(path, tx.ID)
+
+	hasFrom := tx.From != "" || tx.FromAccountID != 0
+	hasTo := tx.To != "" || tx.ToAccountID != 0
+
+	value := tx.Value
+	switch tx.Type {
+	case "transfer":
+		v.account(path, "from", tx.From, tx.FromAccountID)
+		v.account(path, "to", tx.To, tx.ToAccountID)
+		if tx.ToAddress != "" || len(tx.Outputs) > 0 {
+			v.add("%s: transfers do not pay addresses", path)
+		}
+	case "debit":
+		v.account(path, "from", tx.From, tx.FromAccountID)
+		if hasTo {
+			v.add("%s: debits pay addresses, not accounts", path)
+		}
+		switch {
+		case tx.ToAddress != "" && len(tx.Outputs) > 0:
+			v.add("%s: only one of toAddress and outputs can be set", path)
+		case tx.ToAddress != "":
+			v.validateAddress(path+".toAddress", tx.ToAddress)
+		case len(tx.Outputs) > 0:
+			total := int64(0)
+			for i, out := range tx.Outputs {
+				outPath := fmt.Sprintf("%s.outputs[%d]", path, i)
+				v.validateAddress(outPath+".toAddress", out.ToAddress)
+				if out.Value <= 0 {
+					v.add("%s.value: must be > 0", outPath)
+				}
+				total += out.Value
+			}
+			if value == 0 {
+				value = total
+			} else if value != total {
+				v.add("%s.value: %d is not the total of the outputs, %d",
+					path, value, total)
+			}
+		default:
+			v.add("%s: toAddress or outputs is required", path)
+		}
+	case "credit":
+		i, found := v.account(path, "to", tx.To, tx.ToAccountID)
+		if hasFrom {
+			v.add("%s: credits are not paid from accounts", path)
+		}
+		if len(tx.Outputs) > 0 {
+			v.add("%s: credits pay toAddress, not outputs", path)
+		}
+		if tx.ToAddress != "" {
+			if owner, exists := v.owners[tx.ToAddress]; !exists ||
+				(found && owner != i) {
+				v.add("%s.toAddress: %q is not an address of the account",
+					path, tx.ToAddress)
+			}
+		}
+	case "":
+		v.add("%s.type: is required", path)
+	default:
+		v.add("%s.type: %q must be transfer, debit or credit", path, tx.Type)
+	}
+
+	if value <= 0 {
+		v.add("%s.value: must be > 0", path)
+	}
+	if tx.Fee < 0 {
+		v.add("%s.fee: must be >= 0", path)
+	}
+	if tx.Type == "transfer" && (tx.TxHash != "" || tx.FromAddress != "") {
+		v.add("%s: transfers do not have on-chain transactions", path)
+	}
+	if tx.TxHash != "" {
+		if _, err := chainhash.NewHashFromStr(tx.TxHash); err != nil {
+			v.add("%s.txHash: invalid hash %q", path, tx.TxHash)
+		}
+	}
+	if tx.Vout < 0 {
+		v.add("%s.vout: must be >= 0", path)
+	} else if tx.Vout > 0 && tx.Type != "credit" {
+		v.add("%s.vout: only credits have a vout", path)
+	}
+}
+
+// Seed is an option that can be passed to New() to load fixtures into the
+// networks they describe. Networks return to this state when reset. It panics
+// if the fixtures are invalid, so use ReadFixtures or Validate to report
+// their problems.
+func Seed(fixtures Fixtures) Option {
+	if err := fixtures.Validate(); err != nil {
+		panic(err)
+	}
+	return func(c *chain) {
+		if f, exists := fixtures[c.network]; exists {
+			if err := c.seed(f); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// seed loads the validated fixtures f into the chain.
+func (c *chain) seed(f NetworkFixtures) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(f.Fees) > 0 {
+		c.fees = make([]fee, len(f.Fees))
+		for i, fee := range f.Fees {
+			c.fees[i].feePerByte = fee.FeePerByte
+			c.fees[i].blockHeight = fee.BlockHeight
+		}
+	}
+
+	for _, url := range f.Hooks {
+		c.hooks[url] = struct{}{}
+	}
+
+	accIDs := make([]int64, len(f.Accounts))
+	for i, acc := range f.Accounts {
+		id := acc.ID
+		if id == 0 {
+			id = c.nextID()
+		} else {
+			c.ids[id] = struct{}{}
+		}
+		accIDs[i] = c.addAccount(id, 0).id
+
+		if acc.Label != "" {
+			c.accountLabels[acc.Label] = id
+		}
+		for _, addr := range acc.Addresses {
+			c.addresses[addr] = id
+		}
+	}
+
+	accountID := func(label string, id int64) int64 {
+		if label != "" {
+			return c.accountLabels[label]
+		}
+		return id
+	}
+	for i, tf := range f.Transactions {
+		tx := transaction{
+			id:            tf.ID,
+			ty:            tf.Type,
+			fromAccountID: accountID(tf.From, tf.FromAccountID),
+			toAccountID:   accountID(tf.To, tf.ToAccountID),
+			value:         tf.Value,
+			fee:           tf.Fee,
+			txHash:        tf.TxHash,
+			fromAddress:   tf.FromAddress,
+			created:       tf.Created,
+		}
+		if tf.ToAddress != "" || tf.Vout != 0 {
+			tx.outputs = []output{{address: tf.ToAddress, value: tf.Value,
+				txIndex: tf.Vout}}
+		}
+		for j, out := range tf.Outputs {
+			tx.outputs = append(tx.outputs, output{
+				address: out.ToAddress,
+				value:   out.Value,
+				txIndex: int64(j),
+			})
+			if tf.Value == 0 {
+				tx.value += out.Value
+			}
+		}
+		if _, err := c.inject(tx); err != nil {
+			return fmt.Errorf("%s.transactions[%d]: %v", c.network, i, err)
+		}
+	}
+
+	for i, acc := range f.Accounts {
+		a := c.accounts[accIDs[i]]
+		if acc.Balance != nil {
+			a.balance = *acc.Balance
+		}
+		a.frozen = acc.Frozen
+		c.accounts[a.id] = a
+	}
+	return nil
+}
+
+// Fixtures returns fixtures that recreate the fee table, hooks, accounts and
+// complete transactions of the chain. Blocks, unconfirmed credits and unused
+// transaction IDs are not included.
+func (c *chain) Fixtures() NetworkFixtures {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	f := NetworkFixtures{}
+	for _, fee := range c.fees {
+		f.Fees = append(f.Fees, FeeFixture{
+			FeePerByte:  fee.feePerByte,
+			BlockHeight: fee.blockHeight,
+		})
+	}
+	for url := range c.hooks {
+		f.Hooks = append(f.Hooks, url)
+	}
+	sort.Strings(f.Hooks)
+
+	labels := make(map[int64]string)
+	for label, id := range c.accountLabels {
+		labels[id] = label
+	}
+	addresses := make(map[int64][]string)
+	for addr, accID := range c.addresses {
+		addresses[accID] = append(addresses[accID], addr)
+	}
+	for _, id := range c.orderedAccountIDs {
+		acc := c.accounts[id]
+		sort.Strings(addresses[id])
+		f.Accounts = append(f.Accounts, AccountFixture{
+			ID:        id,
+			Label:     labels[id],
+			Balance:   &acc.balance,
+			Frozen:    acc.frozen,
+			Addresses: addresses[id],
+		})
+	}
+
+	for _, id := range c.orderedTransactionIDs {
+		tx := c.transactions[id]
+		if tx.status != "complete" {
+			continue
+		}
+		tf := TransactionFixture{
+			ID:          tx.id,
+			Type:        tx.ty,
+			Value:       tx.value,
+			Fee:         tx.fee,
+			TxHash:      tx.txHash,
+			FromAddress: tx.fromAddress,
+			Created:     tx.created,
+		}
+		switch tx.ty {
+		case "transfer":
+			tf.FromAccountID = tx.fromAccountID
+			tf.ToAccountID = tx.toAccountID
+		case "debit":
+			tf.FromAccountID = tx.fromAccountID
+			for _, out := range tx.outputs {
+				tf.Outputs = append(tf.Outputs, OutputFixture{
+					ToAddress: out.address,
+					Value:     out.value,
+				})
+			}
+		case "credit":
+			tf.ToAccountID = tx.toAccountID
+			tf.Vout = tx.vout()
+			if len(tx.outputs) > 0 {
+				tf.ToAddress = tx.outputs[0].address
+			}
+		}
+		f.Transactions = append(f.Transactions, tf)
+	}
+	return f
+}
+
+// Fixtures returns fixtures that recreate the current state of every network
+// when passed to Seed. Blocks, unconfirmed credits and unused transaction IDs
+// are not included.
+func (s *Service) Fixtures() Fixtures {
+	f := Fixtures{}
+	for network, c := range s.chains {
+		f[network] = c.Fixtures()
+	}
+	return f
+}
+
+// getFixturesHandler responds with fixtures recreating the state of the
+// chain. The payload is a fixture file in JSON, which is valid YAML.
+func (c *chain) getFixturesHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "fixtures", "",
+		[]Fixtures{{c.network: c.Fixtures()}})
+}
@@ -0,0 +1,218 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/service"
+)
+
+const fixtures = `
+mainnet:
+  fees:
+    - feePerByte: 120
+      blockHeight: 451000
+  hooks:
+    - https://example.com/hook
+  accounts:
+    - label: alice
+      addresses: [1BoatSLRHtKNngkdXEeobR76b53LETtpyT]
+    - id: 42
+      label: bob
+      balance: 250
+  transactions:
+    - type: credit
+      to: alice
+      toAddress: 1BoatSLRHtKNngkdXEeobR76b53LETtpyT
+      value: 5000
+      created: 2020-01-02T15:04:05Z
+    - type: transfer
+      from: alice
+      toAccountID: 42
+      value: 1000
+    - type: debit
+      from: alice
+      outputs:
+        - toAddress: 1dice8EMZmqKvrGE4Qc9bUFf9PX3xaYDp
+          value: 300
+`
+
+func TestSeed(t *testing.T) {
+	f, err := service.ReadFixtures(strings.NewReader(fixtures))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := service.New(service.Seed(f))
+
+	alice, ok := s.AccountByLabel(service.MainNet, "alice")
+	if !ok || alice.Balance != 3700 {
+		t.Fatalf("unexpected account %+v", alice)
+	}
+	if b := balance(t, s, 42); b != 250 {
+		t.Fatalf("expected balance 250 got %d", b)
+	}
+	if _, ok := s.AccountByLabel(service.MainNet, "_fee"); !ok {
+		t.Fatal("expected fee account")
+	}
+
+	state := s.State(service.MainNet)
+	if len(state.Transactions) != 3 ||
+		!state.Transactions[0].Created.Equal(
+			time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)) ||
+		state.Transactions[2].Value != 300 {
+		t.Fatalf("unexpected transactions %+v", state.Transactions)
+	}
+	if !reflect.DeepEqual(state.Hooks, []string{"https://example.com/hook"}) {
+		t.Fatalf("unexpected hooks %v", state.Hooks)
+	}
+
+	w := serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), `"feePerByte":120`) {
+		t.Fatalf("unexpected fees %s", w.Body.String())
+	}
+
+	// Fixtures are restored by a reset.
+	if _, err := s.Transfer(service.MainNet, alice.ID, 42, 100); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset(service.MainNet)
+	if b := balance(t, s, 42); b != 250 {
+		t.Fatalf("expected balance 250 got %d", b)
+	}
+}
+
+func TestFixturesInvalid(t *testing.T) {
+	_, err := service.ReadFixtures(strings.NewReader(`
+mainnet:
+  fees:
+    - feePerByte: 0
+  hooks: [ftp://example.com]
+  accounts:
+    - label: alice
+      addresses: [mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn]
+    - label: alice
+  transactions:
+    - type: transfer
+      from: alice
+      to: carol
+      value: 10
+    - type: debit
+      fromAccountID: 7
+      toAddress: 1dice8EMZmqKvrGE4Qc9bUFf9PX3xaYDp
+    - type: refund
+      to: alice
+      value: 1
+testnet: {}
+`))
+	e, ok := err.(*service.FixtureError)
+	if !ok {
+		t.Fatalf("expected fixture error got %v", err)
+	}
+	expected := []string{
+		`mainnet.fees[0].feePerByte: must be > 0`,
+		`mainnet.hooks[0]: invalid url "ftp://example.com"`,
+		`mainnet.accounts[0].addresses[0]: ` +
+			`invalid address "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"`,
+		`mainnet.accounts[1].label: "alice" is already used by accounts[0]`,
+		`mainnet.transactions[0].to: no account is labelled "carol"`,
+		`mainnet.transactions[1].fromAccountID: no account has ID 7`,
+		`mainnet.transactions[1].value: must be > 0`,
+		`mainnet.transactions[2].type: "refund" must be transfer, debit or ` +
+			`credit`,
+		`testnet: unknown network, expected mainnet or testnet3`,
+	}
+	if !reflect.DeepEqual(e.Problems, expected) {
+		t.Fatalf("expected problems\n%s\ngot\n%s",
+			strings.Join(expected, "\n"), strings.Join(e.Problems, "\n"))
+	}
+
+	_, err = service.ReadFixtures(strings.NewReader(`
+mainnet:
+  accounts:
+    - lable: alice
+`))
+	if err == nil || !strings.Contains(err.Error(), "lable") {
+		t.Fatalf("expected unknown field error got %v", err)
+	}
+}
+
+func TestFixturesExport(t *testing.T) {
+	s := service.New()
+
+	from := fundedAccount(t, s, 100000)
+	to := createAccount(t, s)
+	addr := createAddress(t, s, to)
+	if _, err := s.Transfer(service.MainNet, from, to, 500); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+		ID            int64  `json:"id"`
+		FromAccountID int64  `json:"fromAccountID"`
+		ToAddress     string `json:"toAddress"`
+		Value         int64  `json:"value"`
+	}{createTxID(t, s), from, addr, 2000})
+	expectCode(t, w, http.StatusCreated)
+
+	// Credits of several outputs of one on-chain transaction are exported
+	// with their vout.
+	type creditOutput struct {
+		Address string `json:"address"`
+		Value   int64  `json:"value"`
+		Vout    int64  `json:"vout"`
+	}
+	w = serveAdmin(s, "POST", "/_mock/mainnet/addresses/", struct {
+		Outputs []creditOutput `json:"outputs"`
+	}{[]creditOutput{{addr, 30, 0}, {createAddress(t, s, to), 40, 1}}})
+	expectCode(t, w, http.StatusOK)
+	s.Mine(service.MainNet, 1)
+
+	w = serveAdmin(s, "GET", "/_mock/mainnet/fixtures/", nil)
+	expectCode(t, w, http.StatusOK)
+	res := struct {
+		Payload []json.RawMessage
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payload is a fixture file, as is the YAML written by Write.
+	var buf bytes.Buffer
+	if err := s.Fixtures().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{string(res.Payload[0]), buf.String()} {
+		f, err := service.ReadFixtures(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeded := service.New(service.Seed(f))
+
+		expected := s.State(service.MainNet)
+		state := seeded.State(service.MainNet)
+		if !reflect.DeepEqual(state.Accounts, expected.Accounts) {
+			t.Fatalf("expected accounts %+v got %+v", expected.Accounts,
+				state.Accounts)
+		}
+		if len(state.Transactions) != len(expected.Transactions) {
+			t.Fatalf("expected %d transactions got %d",
+				len(expected.Transactions), len(state.Transactions))
+		}
+		for i, tx := range state.Transactions {
+			tx.Confirmations = expected.Transactions[i].Confirmations
+			if !tx.Created.Equal(expected.Transactions[i].Created) {
+				t.Fatalf("unexpected time %v", tx.Created)
+			}
+			tx.Created = expected.Transactions[i].Created
+			if !reflect.DeepEqual(tx, expected.Transactions[i]) {
+				t.Fatalf("expected transaction %+v got %+v",
+					expected.Transactions[i], tx)
+			}
+		}
+	}
+}
@@ -1,44 +1,370 @@
 package service
 
 import (
+	"errors"
+	"net/http"
+
 	"github.com/gorilla/mux"
 )
 
-func (c *chain) handler(router *mux.Router) {
+// route is an endpoint. Its request and response types describe it in the
+// OpenAPI document served at /_mock/[network]/openapi.json.
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	summary string
+
+	// query lists the names of the query parameters.
+	query []string
+
+	// request is the JSON request body, if any. If batch is set the body can
+	// also be an array of request.
+	request interface{}
+	batch   bool
+
+	// status is the status of a successful response. If ty is set the
+	// response is a JSON message of that type with an array of response as
+	// its payload.
+	status   int
+	ty       string
+	response interface{}
+
+	deprecated bool
+}
+
+// handle adds routes, wrapped in mw, to router.
+func handle(router *mux.Router, mw middleware, routes []route) {
+	for _, rt := range routes {
+		router.Handle(rt.path, mw.Handler(rt.handler)).Methods(rt.method)
+	}
+}
+
+// routes returns the endpoints of the production service.
+func (c *chain) routes() []route {
+	return []route{
+		{method: "POST", path: "/accounts/",
+			handler: c.postAccountsHandler,
+			summary: "Create an account",
+			status:  http.StatusCreated, ty: "accounts",
+			response: Account{}},
+		{method: "GET", path: "/accounts/",
+			handler: c.getAccountsHandler,
+			summary: "List accounts",
+			query:   []string{"limit", "next"},
+			status:  http.StatusOK, ty: "accounts",
+			response: Account{}},
+		{method: "GET", path: "/accounts/labels/{account-label:_?[0-9a-zA-Z]+}/",
+			handler: c.getAccountByLabelHandler,
+			summary: "Get an account by its label",
+			status:  http.StatusOK, ty: "accounts",
+			response: Account{}},
+		{method: "GET", path: "/accounts/{account-id:[0-9]+}",
+			handler: c.getAccountHandler,
+			summary: "Get an account",
+			status:  http.StatusOK, ty: "accounts",
+			response: Account{}},
+		{method: "POST", path: "/accounts/{account-id:[0-9]+}/addresses/",
+			handler: c.postAccountAddresses,
+			summary: "Create an address for an account",
+			status:  http.StatusCreated, ty: "addresses",
+			response: addressPayload{}},
+		{method: "GET", path: "/accounts/{account-id:[0-9]+}/transactions/",
+			handler: c.getAccountTransactions,
+			summary: "List the transactions of an account",
+			query:   []string{"limit", "next"},
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+
+		{method: "POST", path: "/transactions/",
+			handler: c.postTransactionsHandler,
+			summary: "Create transaction IDs",
+			request: countPayload{},
+			status:  http.StatusCreated, ty: "transactions",
+			response: transactionIDPayload{}},
+		{method: "PUT", path: "/transactions/",
+			handler: c.putTransactionsHandler,
+			summary: "Transfer between accounts or debit to addresses",
+			request: putTransactionPayload{}, batch: true,
+			status: http.StatusCreated},
+		{method: "GET", path: "/transactions/{transaction-id:[0-9]+}",
+			handler: c.getTransactionHandler,
+			summary: "Get a transaction",
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+		{method: "DELETE", path: "/transactions/{transaction-id:[0-9]+}",
+			handler: c.deleteTransactionHandler,
+			summary: "Cancel an unused transaction ID",
+			status:  http.StatusOK},
+
+		{method: "POST", path: "/hooks/",
+			handler: c.postHookHandler,
+			summary: "Create a hook",
+			request: hookPayload{},
+			status:  http.StatusCreated},
+		{method: "GET", path: "/hooks/",
+			handler: c.getHooksHandler,
+			summary: "List hooks",
+			status:  http.StatusOK, ty: "hooks",
+			response: hookPayload{}},
+		{method: "DELETE", path: "/hooks/{url}",
+			handler: c.deleteHookHandler,
+			summary: "Delete a hook given its base64 URL encoded URL",
+			status:  http.StatusOK},
+
+		{method: "GET", path: "/fees/",
+			handler: c.getFeesHandler,
+			summary: "List fees",
+			status:  http.StatusOK, ty: "fees",
+			response: feePayload{}},
+
+		// The endpoint below is used for testing and does not represent an
+		// actual production service end point. It is kept for existing
+		// clients and is also found under /_mock/[network]/.
+		{method: "POST", path: "/addresses/{address}",
+			handler: c.postAddressHandler,
+			summary: "Credit an address",
+			request: creditPayload{},
+			status:  http.StatusOK, ty: "transactions",
+			response:   Transaction{},
+			deprecated: true},
+	}
+}
+
+// adminRoutes returns the endpoints used to orchestrate tests against the
+// chain. They do not exist in the production service.
+func (c *chain) adminRoutes() []route {
+	return []route{
+		{method: "POST", path: "/reset/",
+			handler: c.postResetHandler,
+			summary: "Reset the network to its initial state",
+			status:  http.StatusOK},
+		{method: "GET", path: "/state/",
+			handler: c.getStateHandler,
+			summary: "Get the state of the network",
+			status:  http.StatusOK, ty: "state",
+			response: State{}},
+
+		{method: "GET", path: "/credentials/",
+			handler: c.getCredentialsHandler,
+			summary: "List credentials",
+			status:  http.StatusOK, ty: "credentials",
+			response: Credential{}},
+		{method: "POST", path: "/credentials/",
+			handler: c.postCredentialsHandler,
+			summary: "Add a credential",
+			request: Credential{},
+			status:  http.StatusCreated},
+
+		{method: "POST", path: "/snapshots/",
+			handler: c.postSnapshotsHandler,
+			summary: "Save the state of the network",
+			status:  http.StatusCreated, ty: "snapshots",
+			response: snapshotPayload{}},
+		{method: "POST", path: "/snapshots/{snapshot-id:[0-9]+}/restore",
+			handler: c.postRestoreHandler,
+			summary: "Restore a snapshot",
+			status:  http.StatusOK},
+		{method: "DELETE", path: "/snapshots/{snapshot-id:[0-9]+}",
+			handler: c.deleteSnapshotHandler,
+			summary: "Delete a snapshot",
+			status:  http.StatusOK},
+
+		{method: "GET", path: "/time/",
+			handler: c.getTimeHandler,
+			summary: "Get the time of the network",
+			status:  http.StatusOK, ty: "time",
+			response: timePayload{}},
+		{method: "POST", path: "/time/",
+			handler: c.postTimeHandler,
+			summary: "Set or advance the time of the network",
+			request: setTimePayload{},
+			status:  http.StatusOK, ty: "time",
+			response: timePayload{}},
+
+		{method: "POST", path: "/addresses/{address}",
+			handler: c.postAddressHandler,
+			summary: "Credit an address",
+			request: creditPayload{},
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+		{method: "POST", path: "/addresses/",
+			handler: c.postAddressesHandler,
+			summary: "Credit the outputs of an on-chain transaction",
+			request: creditPayload{},
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+		{method: "POST", path: "/blocks/",
+			handler: c.postBlocksHandler,
+			summary: "Mine blocks",
+			request: countPayload{},
+			status:  http.StatusCreated, ty: "blocks",
+			response: Block{}},
+		{method: "POST", path: "/reorgs/",
+			handler: c.postReorgsHandler,
+			summary: "Remove the most recent blocks",
+			request: reorgPayload{},
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+		{method: "POST", path: "/doublespends/",
+			handler: c.postDoubleSpendsHandler,
+			summary: "Reverse the credits of an on-chain transaction",
+			request: doubleSpendPayload{},
+			status:  http.StatusOK, ty: "transactions",
+			response: Transaction{}},
+
+		{method: "GET", path: "/fixtures/",
+			handler: c.getFixturesHandler,
+			summary: "Get fixtures recreating the state of the network",
+			status:  http.StatusOK, ty: "fixtures",
+			response: Fixtures{}},
+
+		{method: "GET", path: "/faults/",
+			handler: c.getFaultsHandler,
+			summary: "List fault rules",
+			status:  http.StatusOK, ty: "faults",
+			response: faultRulePayload{}},
+		{method: "PUT", path: "/faults/",
+			handler: c.putFaultsHandler,
+			summary: "Replace the fault rules",
+			request: []faultRulePayload{},
+			status:  http.StatusCreated},
+		{method: "DELETE", path: "/faults/",
+			handler: c.deleteFaultsHandler,
+			summary: "Remove every fault rule",
+			status:  http.StatusOK},
+
+		{method: "GET", path: "/ratelimits/",
+			handler: c.getRateLimitsHandler,
+			summary: "List rate limits",
+			status:  http.StatusOK, ty: "ratelimits",
+			response: RateLimit{}},
+		{method: "PUT", path: "/ratelimits/",
+			handler: c.putRateLimitsHandler,
+			summary: "Replace the rate limits",
+			request: []RateLimit{},
+			status:  http.StatusCreated},
+		{method: "DELETE", path: "/ratelimits/",
+			handler: c.deleteRateLimitsHandler,
+			summary: "Remove every rate limit",
+			status:  http.StatusOK},
+
+		{method: "GET", path: "/journal/",
+			handler: c.getJournalHandler,
+			summary: "List the requests received",
+			status:  http.StatusOK, ty: "journal",
+			response: JournalEntry{}},
+		{method: "DELETE", path: "/journal/",
+			handler: c.deleteJournalHandler,
+			summary: "Clear the journal",
+			status:  http.StatusOK},
+
+		{method: "GET", path: "/stubs/",
+			handler: c.getStubsHandler,
+			summary: "List stubs",
+			status:  http.StatusOK, ty: "stubs",
+			response: Stub{}},
+		{method: "POST", path: "/stubs/",
+			handler: c.postStubsHandler,
+			summary: "Add a stub",
+			request: Stub{},
+			status:  http.StatusCreated, ty: "stubs",
+			response: Stub{}},
+		{method: "DELETE", path: "/stubs/",
+			handler: c.deleteStubsHandler,
+			summary: "Remove every stub",
+			status:  http.StatusOK},
+		{method: "DELETE", path: "/stubs/{stub-id:[0-9]+}",
+			handler: c.deleteStubHandler,
+			summary: "Remove a stub",
+			status:  http.StatusOK},
+	}
+}
+
+// notFoundHandler returns a handler for requests that router does not match.
+// Those matching the path of a route but not its method are sent a 405
+// Method Not Allowed error and others are passed to next. Versions of mux
+// differ in whether they detect a method mismatch under a subrouter, so it is
+// checked here rather than left to router.MethodNotAllowedHandler.
+func notFoundHandler(router *mux.Router, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pathMatched(router, r) {
+			methodNotAllowedHandler(w, r)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// pathMatched reports whether a route of router matches r with another method.
+func pathMatched(router *mux.Router, r *http.Request) bool {
+	matched := false
+	router.Walk(func(route *mux.Route, _ *mux.Router,
+		_ []*mux.Route) error {
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method == r.Method {
+				continue
+			}
+			req := r.Clone(r.Context())
+			req.Method = method
+			var match mux.RouteMatch
+			if route.Match(req, &match) && match.MatchErr == nil {
+				matched = true
+				return errWalkDone
+			}
+		}
+		return nil
+	})
+	return matched
+}
+
+// errWalkDone stops a walk of a router early.
+var errWalkDone = errors.New("walk done")
+
+// handler returns the endpoints of the chain found under prefix.
+func (c *chain) handler(prefix string) http.Handler {
+	root := mux.NewRouter()
+	root.NotFoundHandler = notFoundHandler(root, routeNotFoundHandler)
+	root.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	router := root.PathPrefix(prefix).Subrouter()
+
+	mw := middleware{
+		c.rateLimitMiddleware(prefix),
+		c.strictMiddleware(),
+	}
+	handle(router, mw, c.validated(c.routes()))
+
+	// Middleware applied to every request to the chain, including those that
+	// do not match an endpoint. Requests are authenticated before faults and
+	// stubs apply so that they never answer a request the service would
+	// reject.
+	chainMW := middleware{
+		c.journalMiddleware(prefix),
+		c.authMiddleware(Full),
+		c.faultMiddleware(prefix),
+		c.stubMiddleware(prefix),
+	}
+	return chainMW.Handler(root.ServeHTTP)
+}
+
+// adminHandler returns the endpoints, found under prefix, used to orchestrate
+// tests against the chain.
+func (c *chain) adminHandler(prefix string) http.Handler {
+	root := mux.NewRouter()
+	root.NotFoundHandler = notFoundHandler(root, routeNotFoundHandler)
+	root.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	router := root.PathPrefix(prefix).Subrouter()
+
 	mw := middleware{
-		c.basicAuthMiddleware(),
+		c.authMiddleware(Admin),
+		c.strictMiddleware(),
 	}
+	handle(router, mw, c.validated(c.adminRoutes()))
 
-	// The handler below is used for testing and does not represent an actual
-	accounts := router.PathPrefix("/accounts").Subrouter()
-	accounts.Handle("/", mw.Handler(c.postAccountsHandler)).Methods("POST")
-	accounts.Handle("/", mw.Handler(c.getAccountsHandler)).Methods("GET")
-	accounts.Handle("/labels/{account-label:_?[0-9a-zA-Z]+}/",
-		mw.Handler(c.getAccountByLabelHandler)).Methods("GET")
-	accounts.Handle("/{account-id:[0-9]+}",
-		mw.Handler(c.getAccountHandler)).Methods("GET")
-	accounts.Handle("/{account-id:[0-9]+}/addresses/",
-		mw.Handler(c.postAccountAddresses)).Methods("POST")
-	accounts.Handle("/{account-id:[0-9]+}/transactions/",
-		mw.Handler(c.getAccountTransactions)).Methods("GET")
-
-	transactions := router.PathPrefix("/transactions").Subrouter()
-	transactions.Handle("/",
-		mw.Handler(c.postTransactionsHandler)).Methods("POST")
-	transactions.Handle("/",
-		mw.Handler(c.putTransactionsHandler)).Methods("PUT")
-	transactions.Handle("/{transaction-id:[0-9]+}",
-		mw.Handler(c.getTransactionHandler)).Methods("GET")
-
-	hooks := router.PathPrefix("/hooks").Subrouter()
-	hooks.Handle("/", mw.Handler(c.postHookHandler)).Methods("POST")
-	hooks.Handle("/", mw.Handler(c.getHooksHandler)).Methods("GET")
-	hooks.Handle("/{url}", mw.Handler(c.deleteHookHandler)).Methods("DELETE")
-
-	fees := router.PathPrefix("/fees").Subrouter()
-	fees.Handle("/", mw.Handler(c.getFeesHandler)).Methods("GET")
-
-	// production service end point.
-	router.Handle("/addresses/{address}",
-		mw.Handler(c.postAddressHandler)).Methods("POST")
+	return root
 }
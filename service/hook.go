@@ -2,7 +2,6 @@ package service
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"net/http"
 	"net/url"
 
@@ -19,54 +18,58 @@ var (
 		"http":  true,
 		"https": true,
 	}
+
+	errInvalidURL = newError("invalid_url", "invalid url")
 )
 
-func (c *chain) postHookHandler(w http.ResponseWriter, r *http.Request) {
+type hookPayload struct {
+	URL string `json:"url"`
+}
 
-	if !contentTypeHeaderFound(w, r) {
-		return
+// checkHookURL returns an error if u can not be used as a hook.
+func checkHookURL(u string) error {
+	if len(u) > maxHookURLLength {
+		return newError("invalid_url", "url too long")
 	}
 
-	pl := struct {
-		URL string `json:"url"`
-	}{}
-
-	if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	url, err := url.ParseRequestURI(u)
+	if err != nil {
+		return errInvalidURL
 	}
-
-	if len(pl.URL) > maxHookURLLength {
-		http.Error(w, "url too long", http.StatusBadRequest)
-		return
+	if !validHookURLSchemes[url.Scheme] || url.Host == "" {
+		return errInvalidURL
 	}
+	return nil
+}
 
-	url, err := url.ParseRequestURI(pl.URL)
-	if err != nil {
-		http.Error(w, "invalid url", http.StatusBadRequest)
+func (c *chain) postHookHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !contentTypeHeaderFound(w, r) {
 		return
 	}
 
-	if len(c.hooks) >= maxHooks {
-		http.Error(w, "max hooks reached", http.StatusBadRequest)
+	pl := hookPayload{}
+
+	if err := c.decodeJSON(r.Body, &pl); err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if !validHookURLSchemes[url.Scheme] {
-		http.Error(w, "invalid url", http.StatusBadRequest)
+	if err := checkHookURL(pl.URL); err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if url.Host == "" {
-		http.Error(w, "invalid url", http.StatusBadRequest)
+	if len(c.hooks) >= maxHooks {
+		sendError(w, http.StatusBadRequest, errMaxHooks)
 		return
 	}
 
 	if err := c.CreateHook(pl.URL); err == errHookExists {
-		sendError(w, http.StatusBadRequest, "hook exists")
+		sendError(w, http.StatusBadRequest, err)
 		return
 	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -78,15 +81,9 @@ func (c *chain) getHooksHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	hooks := c.Hooks()
-	pl := make([]struct {
-		URL string `json:"url"`
-	}, len(hooks))
+	pl := make([]hookPayload, len(hooks))
 	for i, h := range hooks {
-		pl[i] = struct {
-			URL string `json:"url"`
-		}{
-			URL: h,
-		}
+		pl[i] = hookPayload{URL: h}
 	}
 	sendPayload(w, http.StatusOK, "hooks", "", pl)
 }
@@ -95,13 +92,14 @@ func (c *chain) deleteHookHandler(w http.ResponseWriter, r *http.Request) {
 
 	encodedURL := mux.Vars(r)["url"]
 	if encodedURL == "" {
-		http.Error(w, "", http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, errInvalidURL)
 		return
 	}
 
 	urlBytes, err := base64.URLEncoding.DecodeString(encodedURL)
 	if err != nil {
-		http.Error(w, "url not base64 encoded", http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest,
+			newError("invalid_url", "url not base64 encoded"))
 		return
 	}
 	url := string(urlBytes)
@@ -3,9 +3,11 @@ package service_test
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/rtwire/mock/service"
 )
@@ -54,3 +56,99 @@ func TestHooks(t *testing.T) {
 		t.Fatalf("expected %v got %v", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestHookCreditEvents(t *testing.T) {
+	s := service.New()
+
+	// Every hook is sent the whole event.
+	bodies := make(chan []byte, 2)
+	for i := 0; i < 2; i++ {
+		hook := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				bodies <- body
+			}))
+		defer hook.Close()
+
+		w := serve(s, "POST", "/v1/mainnet/hooks/", struct {
+			URL string `json:"url"`
+		}{hook.URL})
+		expectCode(t, w, http.StatusCreated)
+	}
+
+	fundedAccount(t, s, 10)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case body := <-bodies:
+			event := struct {
+				Type    string
+				Payload []struct {
+					Value int64
+				}
+			}{}
+			if err := json.Unmarshal(body, &event); err != nil {
+				t.Fatalf("unexpected event %q: %v", body, err)
+			}
+			if event.Type != "transactions" || len(event.Payload) != 1 ||
+				event.Payload[0].Value != 10 {
+				t.Fatalf("unexpected event %+v", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an event for every hook")
+		}
+	}
+}
+
+func TestHookUnconfirmedCredit(t *testing.T) {
+	s := service.New()
+
+	statuses := make(chan string, 2)
+	hook := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			event := struct {
+				Payload []struct {
+					Status string
+				}
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil ||
+				len(event.Payload) != 1 {
+				statuses <- "invalid"
+				return
+			}
+			statuses <- event.Payload[0].Status
+		}))
+	defer hook.Close()
+
+	w := serve(s, "POST", "/v1/mainnet/hooks/", struct {
+		URL string `json:"url"`
+	}{hook.URL})
+	expectCode(t, w, http.StatusCreated)
+
+	accID := createAccount(t, s)
+	addr := createAddress(t, s, accID)
+
+	if _, err := s.Credit(service.MainNet, addr, 10, 101); err == nil {
+		t.Fatal("expected an error for too many confirmations")
+	}
+
+	// The hook is only sent the credit once it is mined.
+	if _, err := s.Credit(service.MainNet, addr, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case status := <-statuses:
+		t.Fatalf("unexpected %s event before the credit is mined", status)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Mine(service.MainNet, 1)
+	select {
+	case status := <-statuses:
+		if status != "complete" {
+			t.Fatalf("expected complete event got %s", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an event once the credit is mined")
+	}
+}
@@ -1,15 +1,12 @@
 package service
 
-import (
-	"fmt"
-	"net/http"
-)
+import "net/http"
 
 func headerFound(w http.ResponseWriter, r *http.Request,
 	key, value string) bool {
 	if r.Header.Get(key) != value {
-		errStr := fmt.Sprintf("header %v: %v not found", key, value)
-		http.Error(w, errStr, http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, errorf("header_not_found",
+			"header %v: %v not found", key, value))
 		return false
 	}
 	return true
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const maxJournalEntries = 10000
+
+// JournalEntry records a request received by the service and the response
+// that was sent.
+type JournalEntry struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+
+	// Status is zero if the connection was dropped without a response.
+	Status int `json:"status"`
+
+	Time time.Time `json:"time"`
+
+	// Duration is the time taken to respond in nanoseconds.
+	Duration time.Duration `json:"duration"`
+
+	// Violations are the ways in which the request and response did not
+	// match the OpenAPI document, if the Validate option is set.
+	Violations []string `json:"violations,omitempty"`
+}
+
+// JournalMatch selects journal entries.
+type JournalMatch struct {
+	// Method is the HTTP method to match. Empty matches every method.
+	Method string
+
+	// Path is a pattern, as used by path.Match, matched against the request
+	// path after /v1/[network]. Empty matches every path.
+	Path string
+
+	// Body holds top level fields that the JSON request body must contain
+	// with the given values. If the body is a JSON array at least one of its
+	// elements must contain the fields.
+	Body map[string]interface{}
+}
+
+func (m JournalMatch) matches(prefix string, e JournalEntry) bool {
+	return requestMatches(m.Method, m.Path, prefix, e.Method, e.Path) &&
+		bodyMatches(m.Body, e.Body)
+}
+
+// requestMatches reports whether a request with method and urlPath matches
+// the method and path pattern. urlPath is matched after removing prefix.
+func requestMatches(method, pattern, prefix,
+	reqMethod, urlPath string) bool {
+
+	if method != "" && !strings.EqualFold(method, reqMethod) {
+		return false
+	}
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, strings.TrimPrefix(urlPath, prefix))
+	return matched
+}
+
+// bodyMatches reports whether the JSON object body, or one of the objects if
+// body is an array, has the top level fields with the given values.
+func bodyMatches(fields map[string]interface{}, body string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return false
+	}
+
+	objects, ok := v.([]interface{})
+	if !ok {
+		objects = []interface{}{v}
+	}
+	for _, obj := range objects {
+		if obj, ok := obj.(map[string]interface{}); ok &&
+			fieldsMatch(fields, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldsMatch(fields, obj map[string]interface{}) bool {
+	for key, value := range fields {
+		field, exists := obj[key]
+		if !exists || fmt.Sprint(field) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestingT is the subset of *testing.T used to report failed assertions.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+func (c *chain) recordJournalEntry(e JournalEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.journal) >= maxJournalEntries {
+		c.journal = c.journal[1:]
+	}
+	c.journal = append(c.journal, e)
+}
+
+func (c *chain) Journal() []JournalEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]JournalEntry, len(c.journal))
+	copy(entries, c.journal)
+	return entries
+}
+
+func (c *chain) ClearJournal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.journal = nil
+}
+
+// statusWriter records the status code written to an http.ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+type journalEntryKey struct{}
+
+// credentialHeaders hold credentials, which are redacted in the journal.
+var credentialHeaders = []string{"Authorization", "X-Api-Key", "Cookie"}
+
+// redactHeader returns a copy of h with the values of credentialHeaders
+// replaced, so the journal shows that credentials were sent but not what they
+// were.
+func redactHeader(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		redacted[name] = values
+	}
+	for _, name := range credentialHeaders {
+		if _, exists := redacted[name]; exists {
+			redacted[name] = []string{"[redacted]"}
+		}
+	}
+	return redacted
+}
+
+// journalMiddleware records every request to endpoints under prefix in the
+// chain's journal.
+func (c *chain) journalMiddleware(
+	prefix string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			e := JournalEntry{
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Query:  r.URL.RawQuery,
+				Header: redactHeader(r.Header),
+				Body:   string(body),
+				Time:   time.Now(),
+			}
+			sw := &statusWriter{ResponseWriter: w}
+
+			// Requests are recorded even if the handler panics to drop the
+			// connection.
+			defer func() {
+				e.Status = sw.status
+				e.Duration = time.Since(e.Time)
+				c.recordJournalEntry(e)
+			}()
+
+			ctx := context.WithValue(r.Context(), journalEntryKey{}, &e)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+		})
+	}
+}
+
+// Journal returns the requests received for network, oldest first. At most
+// the last 10000 requests are kept.
+func (s *Service) Journal(network Network) []JournalEntry {
+	return s.chain(network).Journal()
+}
+
+// ClearJournal removes all requests from the journal of network.
+func (s *Service) ClearJournal(network Network) {
+	s.chain(network).ClearJournal()
+}
+
+// Calls returns the requests received for network that match m.
+func (s *Service) Calls(network Network, m JournalMatch) []JournalEntry {
+	c := s.chain(network)
+	prefix := "/v1/" + c.params().Name
+
+	entries := []JournalEntry{}
+	for _, e := range c.Journal() {
+		if m.matches(prefix, e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// AssertCalls reports an error to t, and returns false, unless exactly n
+// requests received for network match m. For example:
+//
+//	s.AssertCalls(t, service.MainNet, service.JournalMatch{
+//	    Method: "PUT",
+//	    Path:   "/transactions/",
+//	    Body:   map[string]interface{}{"fromAccountID": accID},
+//	}, 1)
+func (s *Service) AssertCalls(t TestingT, network Network,
+	m JournalMatch, n int) bool {
+
+	if calls := s.Calls(network, m); len(calls) != n {
+		t.Errorf("expected %d calls matching %+v got %d", n, m, len(calls))
+		return false
+	}
+	return true
+}
+
+func (c *chain) getJournalHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "journal", "", c.Journal())
+}
+
+func (c *chain) deleteJournalHandler(w http.ResponseWriter, r *http.Request) {
+	c.ClearJournal()
+}
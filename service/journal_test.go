@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+type recordingT struct {
+	errors int
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors++
+}
+
+func TestJournal(t *testing.T) {
+	s := service.New()
+
+	fromAccID := fundedAccount(t, s, 10)
+	toAccID := createAccount(t, s)
+
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}{
+		ID:            createTxID(t, s),
+		FromAccountID: fromAccID,
+		ToAccountID:   toAccID,
+		Value:         5,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	m := service.JournalMatch{
+		Method: "PUT",
+		Path:   "/transactions/",
+		Body: map[string]interface{}{
+			"fromAccountID": fromAccID,
+		},
+	}
+	s.AssertCalls(t, service.MainNet, m, 1)
+
+	calls := s.Calls(service.MainNet, m)
+	if len(calls) == 1 && calls[0].Status != http.StatusCreated {
+		t.Fatalf("expected status %d got %d", http.StatusCreated,
+			calls[0].Status)
+	}
+	if auth := calls[0].Header.Get("Authorization"); auth != "[redacted]" {
+		t.Fatalf("expected redacted credentials got %q", auth)
+	}
+
+	rt := &recordingT{}
+	m.Body["fromAccountID"] = toAccID
+	if s.AssertCalls(rt, service.MainNet, m, 1) || rt.errors != 1 {
+		t.Fatal("expected assertion to fail")
+	}
+
+	// The journal is also available over HTTP.
+	w = serveAdmin(s, "GET", "/_mock/mainnet/journal/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []service.JournalEntry
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != len(s.Journal(service.MainNet)) {
+		t.Fatal("journal mismatch")
+	}
+
+	w = serveAdmin(s, "DELETE", "/_mock/mainnet/journal/", nil)
+	expectCode(t, w, http.StatusOK)
+	if n := len(s.Journal(service.MainNet)); n != 0 {
+		t.Fatalf("expected empty journal got %d entries", n)
+	}
+}
@@ -11,18 +11,3 @@ func (mw middleware) Handler(handler http.HandlerFunc) http.Handler {
 	}
 	return h
 }
-
-func (s *chain) basicAuthMiddleware() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, pass, exists := r.BasicAuth()
-			if !exists || user != s.user || pass != s.pass {
-				http.Error(w, "user pass unauthenticated",
-					http.StatusUnauthorized)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// jsonObject is a JSON object of the OpenAPI document.
+type jsonObject map[string]interface{}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas holds the schemas of named structs, which are referenced from
+// other schemas rather than repeated.
+type schemas map[string]interface{}
+
+// schemaName returns the name of the schema of a named struct, for example
+// "Count" for countPayload.
+func schemaName(t reflect.Type) string {
+	name := []rune(strings.TrimSuffix(t.Name(), "Payload"))
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// of returns the schema of values of t as encoded by encoding/json.
+func (ss schemas) of(t reflect.Type) jsonObject {
+	switch t {
+	case timeType:
+		return jsonObject{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return jsonObject{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := ss.of(t.Elem())
+		if _, exists := s["$ref"]; exists {
+			// Keywords beside a reference are ignored.
+			s = jsonObject{"allOf": []jsonObject{s}}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return ss.object(t)
+		}
+		name := schemaName(t)
+		if _, exists := ss[name]; !exists {
+			ss[name] = jsonObject{}
+			ss[name] = ss.object(t)
+		}
+		return jsonObject{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		return jsonObject{"type": "array", "items": ss.of(t.Elem())}
+	case reflect.Map:
+		return jsonObject{
+			"type":                 "object",
+			"additionalProperties": ss.of(t.Elem()),
+		}
+	case reflect.String:
+		return jsonObject{"type": "string"}
+	case reflect.Bool:
+		return jsonObject{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return jsonObject{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint64:
+		return jsonObject{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return jsonObject{"type": "number", "format": "double"}
+	}
+	return jsonObject{}
+}
+
+func (ss schemas) object(t reflect.Type) jsonObject {
+	properties := jsonObject{}
+	ss.addFields(properties, t)
+	return jsonObject{"type": "object", "properties": properties}
+}
+
+// addFields adds the fields of the struct t, including those promoted from
+// embedded structs, to properties.
+func (ss schemas) addFields(properties jsonObject, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			ss.addFields(properties, f.Type)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = ss.of(f.Type)
+	}
+}
+
+// messageSchema is the schema of a JSON message of type ty with an array of
+// items as its payload.
+func messageSchema(ty string, items jsonObject) jsonObject {
+	return jsonObject{
+		"type":     "object",
+		"required": []string{"type", "payload"},
+		"properties": jsonObject{
+			"type":    jsonObject{"type": "string", "enum": []string{ty}},
+			"next":    jsonObject{"type": "string"},
+			"payload": jsonObject{"type": "array", "items": items},
+		},
+	}
+}
+
+func jsonContent(schema jsonObject) jsonObject {
+	return jsonObject{"application/json": jsonObject{"schema": schema}}
+}
+
+// requestSchema returns the schema of the request body of rt or nil if it
+// does not take one.
+func (ss schemas) requestSchema(rt route) jsonObject {
+	if rt.request == nil {
+		return nil
+	}
+	schema := ss.of(reflect.TypeOf(rt.request))
+	if rt.batch {
+		schema = jsonObject{"oneOf": []jsonObject{
+			schema,
+			{"type": "array", "items": schema},
+		}}
+	}
+	return schema
+}
+
+// responseSchema returns the schema of the body of a successful response to
+// rt or nil if it does not have one.
+func (ss schemas) responseSchema(rt route) jsonObject {
+	switch {
+	case rt.ty != "":
+		return messageSchema(rt.ty, ss.of(reflect.TypeOf(rt.response)))
+	case rt.response != nil:
+		return ss.of(reflect.TypeOf(rt.response))
+	}
+	return nil
+}
+
+// errorsSchema returns the schema of the body of an error response.
+func (ss schemas) errorsSchema() jsonObject {
+	return messageSchema("errors", ss.of(reflect.TypeOf(errorPayload{})))
+}
+
+// pathParameters converts a mux path template, such as
+// "/accounts/{account-id:[0-9]+}", to an OpenAPI path and its parameters.
+func pathParameters(template string) (string, []jsonObject) {
+	var path strings.Builder
+	params := []jsonObject{}
+	for {
+		start := strings.Index(template, "{")
+		end := strings.Index(template, "}")
+		if start < 0 || end < start {
+			path.WriteString(template)
+			return path.String(), params
+		}
+
+		name, pattern := template[start+1:end], ""
+		if i := strings.Index(name, ":"); i >= 0 {
+			name, pattern = name[:i], name[i+1:]
+		}
+		schema := jsonObject{"type": "string"}
+		if pattern != "" {
+			schema["pattern"] = "^" + pattern + "$"
+		}
+		params = append(params, jsonObject{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+
+		path.WriteString(template[:start] + "{" + name + "}")
+		template = template[end+1:]
+	}
+}
+
+// operation describes rt in the OpenAPI document.
+func (ss schemas) operation(rt route, tag string,
+	multiTenant bool) jsonObject {
+
+	_, params := pathParameters(rt.path)
+	for _, name := range rt.query {
+		params = append(params, jsonObject{
+			"name":   name,
+			"in":     "query",
+			"schema": jsonObject{"type": "integer", "format": "int64"},
+		})
+	}
+	if multiTenant {
+		params = append(params, jsonObject{
+			"$ref": "#/components/parameters/tenant",
+		})
+	}
+
+	op := jsonObject{
+		"summary":    rt.summary,
+		"tags":       []string{tag},
+		"parameters": params,
+	}
+	if rt.deprecated {
+		op["deprecated"] = true
+	}
+
+	if schema := ss.requestSchema(rt); schema != nil {
+		op["requestBody"] = jsonObject{
+			"required": true,
+			"content":  jsonContent(schema),
+		}
+	}
+
+	res := jsonObject{"description": http.StatusText(rt.status)}
+	if schema := ss.responseSchema(rt); schema != nil {
+		res["content"] = jsonContent(schema)
+	}
+	op["responses"] = jsonObject{
+		strconv.Itoa(rt.status): res,
+		"default": jsonObject{
+			"$ref": "#/components/responses/error",
+		},
+	}
+	return op
+}
+
+// openAPI returns an OpenAPI 3 document describing the endpoints of the
+// network under /v1/[network] and /_mock/[network].
+func (ts *tenants) openAPI() jsonObject {
+	c := ts.base.chain
+	name := c.params().Name
+
+	ss := schemas{}
+	paths := jsonObject{}
+	add := func(prefix, tag string, routes []route) {
+		for _, rt := range routes {
+			path, _ := pathParameters(prefix + rt.path)
+			item, exists := paths[path].(jsonObject)
+			if !exists {
+				item = jsonObject{}
+				paths[path] = item
+			}
+			item[strings.ToLower(rt.method)] = ss.operation(rt, tag,
+				c.multiTenant)
+		}
+	}
+	add("/v1/"+name, "production", c.routes())
+	add("/_mock/"+name, "mock", append(c.adminRoutes(), ts.routes()...))
+
+	errs := ss.errorsSchema()
+
+	return jsonObject{
+		"openapi": "3.0.3",
+		"info": jsonObject{
+			"title":   "RTWire mock service (" + name + ")",
+			"version": "1",
+		},
+		"tags": []jsonObject{
+			{"name": "production",
+				"description": "Endpoints of the production service"},
+			{"name": "mock",
+				"description": "Endpoints used to orchestrate tests"},
+		},
+		"paths": paths,
+		"security": []jsonObject{
+			{"basicAuth": []string{}},
+			{"bearerAuth": []string{}},
+			{"apiKey": []string{}},
+		},
+		"components": jsonObject{
+			"schemas": ss,
+			"responses": jsonObject{
+				"error": jsonObject{
+					"description": "Error",
+					"content":     jsonContent(errs),
+				},
+			},
+			"parameters": jsonObject{
+				"tenant": jsonObject{
+					"name":        tenantHeader,
+					"in":          "header",
+					"description": "Tenant serving the request",
+					"schema":      jsonObject{"type": "string"},
+				},
+			},
+			"securitySchemes": jsonObject{
+				"basicAuth": jsonObject{"type": "http", "scheme": "basic"},
+				"bearerAuth": jsonObject{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+				"apiKey": jsonObject{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}
+}
+
+func (ts *tenants) getOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, http.StatusOK, ts.openAPI())
+}
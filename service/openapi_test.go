@@ -0,0 +1,227 @@
+package service_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+type openAPIResponse struct {
+	Content map[string]struct {
+		Schema map[string]interface{}
+	}
+}
+
+type openAPIDocument struct {
+	Paths map[string]map[string]struct {
+		Responses map[string]openAPIResponse
+	}
+	Components struct {
+		Schemas map[string]map[string]interface{}
+	}
+}
+
+// validate checks that v, decoded from JSON, matches schema and that every
+// field of its objects is declared.
+func (d openAPIDocument) validate(schema map[string]interface{},
+	v interface{}, at string) error {
+
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		s, exists := d.Components.Schemas[name]
+		if !exists {
+			return fmt.Errorf("%s: schema %s not found", at, name)
+		}
+		return d.validate(s, v, at)
+	}
+	if v == nil && schema["nullable"] == true {
+		return nil
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, s := range allOf {
+			if err := d.validate(s.(map[string]interface{}), v,
+				at); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object got %v", at, v)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, exists := obj[name.(string)]; !exists {
+				return fmt.Errorf("%s: %s required", at, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, value := range obj {
+			s, declared := properties[name].(map[string]interface{})
+			if !declared {
+				s = additional
+			}
+			if s == nil {
+				return fmt.Errorf("%s: %s not declared", at, name)
+			}
+			if err := d.validate(s, value, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array got %v", at, v)
+		}
+		items := schema["items"].(map[string]interface{})
+		for i, value := range arr {
+			err := d.validate(items, value, at+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string got %v", at, v)
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && enum[0] != s {
+			return fmt.Errorf("%s: expected %v got %s", at, enum, s)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer got %v", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected number got %v", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean got %v", at, v)
+		}
+	}
+	return nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	s := service.New(
+		service.Faults(service.MainNet, service.FaultRule{
+			Path:   "/unused/",
+			Status: http.StatusInternalServerError,
+		}),
+		service.RateLimits(service.MainNet, service.RateLimit{
+			Rate:  1000,
+			Burst: 1000,
+		}),
+		service.Credentials(service.MainNet, service.Credential{
+			Token:      "token",
+			AccountIDs: []int64{1},
+		}),
+	)
+
+	w := serveAdmin(s, "GET", "/_mock/mainnet/openapi.json", nil)
+	expectCode(t, w, http.StatusOK)
+	doc := openAPIDocument{}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give every list endpoint something to return.
+	accID := createAccount(t, s)
+	addr := createAddress(t, s, accID)
+	txID := credit(t, s, addr, 1000)
+	const hookURL = "https://example.com/hook"
+	expectCode(t, serve(s, "POST", "/v1/mainnet/hooks/",
+		map[string]string{"url": hookURL}), http.StatusCreated)
+	expectCode(t, serveAdmin(s, "POST", "/_mock/mainnet/blocks/",
+		map[string]int{"n": 1}), http.StatusCreated)
+	stub, err := s.AddStub(service.MainNet, service.Stub{
+		Path:   "/unused/",
+		Header: map[string]string{"X-Unused": "1"},
+		Body:   map[string]interface{}{"unused": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := strings.NewReplacer(
+		"{account-id}", strconv.FormatInt(accID, 10),
+		"{account-label}", "_fee",
+		"{transaction-id}", strconv.FormatInt(txID, 10),
+		"{address}", addr,
+		"{url}", base64.URLEncoding.EncodeToString([]byte(hookURL)),
+		"{snapshot-id}", strconv.FormatInt(s.Snapshot(service.MainNet), 10),
+		"{stub-id}", strconv.FormatInt(stub.ID, 10),
+		"{tenant}", "tenant",
+	)
+
+	paths := []string{}
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// GET endpoints are called first so that they return the items created
+	// above, and their responses are checked against the document.
+	for _, path := range paths {
+		op, exists := doc.Paths[path]["get"]
+		if !exists {
+			continue
+		}
+		w := serveAdmin(s, "GET", params.Replace(path), nil)
+		res, exists := op.Responses[strconv.Itoa(w.Code)]
+		if !exists {
+			t.Fatalf("GET %s: undocumented status %d: %s", path, w.Code,
+				w.Body.String())
+		}
+		var body interface{}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		schema := res.Content["application/json"].Schema
+		if err := doc.validate(schema, body, "GET "+path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every documented method of a path is routed and others are not.
+	for _, path := range paths {
+		for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+			_, documented := doc.Paths[path][strings.ToLower(method)]
+			w := serveAdmin(s, method, params.Replace(path), nil)
+
+			res := struct {
+				Payload []struct {
+					Code string
+				}
+			}{}
+			json.NewDecoder(w.Body).Decode(&res)
+			code := ""
+			if len(res.Payload) > 0 {
+				code = res.Payload[0].Code
+			}
+
+			routed := code != "route_not_found" &&
+				code != "method_not_allowed"
+			if routed != documented {
+				t.Fatalf("%s %s: documented %v got %d %s", method, path,
+					documented, w.Code, code)
+			}
+		}
+	}
+}
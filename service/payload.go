@@ -11,35 +11,51 @@ type jsonMessage struct {
 	Payload interface{} `json:"payload"`
 }
 
-func sendError(w http.ResponseWriter, code int, message string) {
-	resp := jsonMessage{
-		Type: "errors",
-		Payload: []struct {
-			Message string `json:"message"`
-		}{
-			{Message: message},
-		},
-	}
+// countPayload is the number of items, such as transaction IDs or blocks, to
+// create.
+type countPayload struct {
+	N int `json:"n"`
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+type errorPayload struct {
+	// Index is the position of the failed item in a batch request.
+	Index   *int   `json:"index,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func sendError(w http.ResponseWriter, code int, err error) {
+	sendErrors(w, code, []errorPayload{newErrorPayload(code, err)})
+}
+
+func sendErrors(w http.ResponseWriter, code int, errs []errorPayload) {
+	sendJSON(w, code, jsonMessage{
+		Type:    "errors",
+		Payload: errs,
+	})
 }
 
 func sendPayload(w http.ResponseWriter, code int,
 	ty, next string, payload interface{}) {
 
-	resp := jsonMessage{
+	sendJSON(w, code, jsonMessage{
 		Type:    ty,
 		Next:    next,
 		Payload: payload,
+	})
+}
+
+// sendJSON sends v as the body of a response with code. v is encoded before
+// the response is written so an encoding failure can be sent as a 500
+// Internal Server Error in the error envelope instead.
+func sendJSON(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	w.Write(append(body, '\n'))
 }
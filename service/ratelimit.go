@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit throttles requests to a network that match its Method and Path
+// using a token bucket per credential. Each request takes a token and tokens
+// are added at Rate per second up to Burst. Requests made without a token
+// receive a 429 Too Many Requests response with a Retry-After header.
+type RateLimit struct {
+	// Method is the HTTP method to match. Empty matches every method.
+	Method string `json:"method"`
+
+	// Path is a pattern, as used by path.Match, matched against the request
+	// path after /v1/[network]. Empty matches every path.
+	Path string `json:"path"`
+
+	// Rate is the number of requests allowed per second.
+	Rate float64 `json:"rate"`
+
+	// Burst is the number of requests that can be made at once. It defaults
+	// to 1.
+	Burst int `json:"burst"`
+}
+
+func (l RateLimit) validate() error {
+	if l.Rate <= 0 {
+		return errors.New("rate must be > 0")
+	}
+	if l.Burst < 0 {
+		return errors.New("burst must be >= 0")
+	}
+	if _, err := path.Match(l.Path, ""); err != nil {
+		return errors.New("invalid path")
+	}
+	return nil
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst == 0 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+var errRateLimited = newError("rate_limited", "rate limit exceeded")
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter is a RateLimit and the buckets of the credentials that have
+// made matching requests.
+type rateLimiter struct {
+	limit   RateLimit
+	buckets map[string]*bucket
+}
+
+// wait refills the bucket of key at now and reports how long until it holds a
+// token, which is zero if it does.
+func (rl *rateLimiter) wait(key string, now time.Time) time.Duration {
+	b, exists := rl.buckets[key]
+	if !exists {
+		b = &bucket{tokens: rl.limit.burst(), updated: now}
+		rl.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(rl.limit.burst(), b.tokens+elapsed*rl.limit.Rate)
+		b.updated = now
+	}
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	seconds := (1 - b.tokens) / rl.limit.Rate
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// SetRateLimits replaces the chain's rate limits, refilling every bucket.
+func (c *chain) SetRateLimits(limits []RateLimit) error {
+	for _, limit := range limits {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setRateLimits(limits)
+	return nil
+}
+
+func (c *chain) setRateLimits(limits []RateLimit) {
+	c.rateLimiters = make([]*rateLimiter, len(limits))
+	for i, limit := range limits {
+		c.rateLimiters[i] = &rateLimiter{
+			limit:   limit,
+			buckets: make(map[string]*bucket),
+		}
+	}
+}
+
+func (c *chain) RateLimits() []RateLimit {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	limits := make([]RateLimit, len(c.rateLimiters))
+	for i, rl := range c.rateLimiters {
+		limits[i] = rl.limit
+	}
+	return limits
+}
+
+// throttle takes a token from the bucket of key for every rate limit matching
+// a request with method and urlPath. If any bucket is empty no tokens are
+// taken and the time until the request can be retried is returned.
+func (c *chain) throttle(key, method, urlPath string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	matched := []*rateLimiter{}
+	retry := time.Duration(0)
+	for _, rl := range c.rateLimiters {
+		if !requestMatches(rl.limit.Method, rl.limit.Path, "", method,
+			urlPath) {
+			continue
+		}
+		matched = append(matched, rl)
+		if wait := rl.wait(key, now); wait > retry {
+			retry = wait
+		}
+	}
+	if retry > 0 {
+		return retry
+	}
+
+	for _, rl := range matched {
+		rl.buckets[key].tokens--
+	}
+	return 0
+}
+
+// rateLimitMiddleware throttles requests to endpoints under prefix according
+// to the chain's rate limits. It must follow authMiddleware as buckets are
+// kept per credential.
+func (c *chain) rateLimitMiddleware(
+	prefix string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cred := requestCredential(r)
+			key := "user:" + cred.User
+			if cred.Token != "" {
+				key = "token:" + cred.Token
+			}
+
+			urlPath := strings.TrimPrefix(r.URL.Path, prefix)
+			retry := c.throttle(key, r.Method, urlPath)
+			if retry > 0 {
+				seconds := int64(math.Ceil(retry.Seconds()))
+				w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+				sendError(w, http.StatusTooManyRequests, errRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRateLimits replaces the rate limits of network.
+func (s *Service) SetRateLimits(network Network, limits ...RateLimit) error {
+	return s.chain(network).SetRateLimits(limits)
+}
+
+// RateLimits is an option that can be passed to New() to throttle requests
+// to the specified network. It panics if a limit is invalid. Limits can also
+// be changed while the service is running using SetRateLimits or the
+// /_mock/[network]/ratelimits/ endpoint.
+func RateLimits(network Network, limits ...RateLimit) Option {
+	return func(c *chain) {
+		if c.network == network {
+			if err := c.SetRateLimits(limits); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func (c *chain) getRateLimitsHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "ratelimits", "", c.RateLimits())
+}
+
+// putRateLimitsHandler replaces the rate limits with the JSON array of limits
+// in the request body.
+func (c *chain) putRateLimitsHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	limits := []RateLimit{}
+	if err := c.decodeJSON(r.Body, &limits); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.SetRateLimits(limits); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (c *chain) deleteRateLimitsHandler(w http.ResponseWriter,
+	r *http.Request) {
+	c.SetRateLimits(nil)
+}
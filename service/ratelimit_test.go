@@ -0,0 +1,48 @@
+package service_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := service.New(service.RateLimits(service.MainNet, service.RateLimit{
+		Method: "GET",
+		Path:   "/fees/",
+		Rate:   0.5,
+		Burst:  2,
+	}))
+
+	for _, code := range []int{
+		http.StatusOK,
+		http.StatusOK,
+		http.StatusTooManyRequests,
+	} {
+		w := serve(s, "GET", "/v1/mainnet/fees/", nil)
+		expectCode(t, w, code)
+		if code == http.StatusTooManyRequests &&
+			w.Header().Get("Retry-After") != "2" {
+			t.Fatalf("expected Retry-After 2 got %q",
+				w.Header().Get("Retry-After"))
+		}
+	}
+
+	// Other routes and credentials have their own buckets.
+	w := serve(s, "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusCreated)
+	w = serveAs(s, "admin", "pass", "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	// Buckets refill with the time of the network.
+	s.Advance(service.MainNet, 2*time.Second)
+	w = serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	w = serveAdmin(s, "DELETE", "/_mock/mainnet/ratelimits/", nil)
+	expectCode(t, w, http.StatusOK)
+	w = serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+}
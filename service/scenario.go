@@ -0,0 +1,343 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Scenario clocks.
+const (
+	// StartClock times the steps of a scenario from when it is played.
+	StartClock = "start"
+
+	// VirtualClock times the steps of a scenario from the time of the
+	// network when it is played, so moving the time of the network, for
+	// example with POST /_mock/[network]/time/, brings steps forward.
+	VirtualClock = "virtual"
+)
+
+// Scenario is a sequence of timed events played against a network by Play.
+// It is usually read from a YAML file with ReadScenario, for example:
+//
+//	network: mainnet
+//	clock: virtual
+//	steps:
+//	  - at: 5s
+//	    credit:
+//	      address: 1BoatSLRHtKNngkdXEeobR76b53LETtpyT
+//	      value: 10000
+//	      confirmations: 0
+//	  - at: 30s
+//	    mine: 1
+//	  - at: 60s
+//	    reorg: 1
+type Scenario struct {
+	// Network defaults to MainNet and Clock to StartClock.
+	Network Network `yaml:"network,omitempty"`
+	Clock   string  `yaml:"clock,omitempty"`
+
+	// Steps must be in the order they are played.
+	Steps []ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioStep is an event of a scenario played At after it starts. Exactly
+// one of Credit, Mine, Reorg, DoubleSpend and Advance is set.
+type ScenarioStep struct {
+	At time.Duration `yaml:"at"`
+
+	Credit *ScenarioCredit `yaml:"credit,omitempty"`
+
+	// Mine is the number of blocks to mine and Reorg the number to remove.
+	Mine  int `yaml:"mine,omitempty"`
+	Reorg int `yaml:"reorg,omitempty"`
+
+	// DoubleSpend is the hash of an on-chain transaction whose credits are
+	// reversed.
+	DoubleSpend string `yaml:"doubleSpend,omitempty"`
+
+	// Advance moves the time of the network forward.
+	Advance time.Duration `yaml:"advance,omitempty"`
+}
+
+// ScenarioCredit credits an address owned by the network, given by Address or
+// the label of its account, as if received from the network. An address of
+// the account is created if it has none. Confirmations defaults to 1.
+type ScenarioCredit struct {
+	Address string `yaml:"address,omitempty"`
+	Account string `yaml:"account,omitempty"`
+	Value   int64  `yaml:"value"`
+
+	Confirmations *int64 `yaml:"confirmations,omitempty"`
+	TxHash        string `yaml:"txHash,omitempty"`
+	FromAddress   string `yaml:"fromAddress,omitempty"`
+}
+
+// ReadScenario reads a YAML scenario from r and validates it.
+func ReadScenario(r io.Reader) (Scenario, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Scenario{}, err
+	}
+	sc := Scenario{}
+	if err := yaml.UnmarshalStrict(b, &sc); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario: %v", err)
+	}
+	if err := sc.validate(); err != nil {
+		return Scenario{}, err
+	}
+	return sc, nil
+}
+
+func (sc Scenario) network() Network {
+	if sc.Network == "" {
+		return MainNet
+	}
+	return sc.Network
+}
+
+func (sc Scenario) validate() error {
+	if _, exists := networkParams[sc.network()]; !exists {
+		return fmt.Errorf("invalid scenario: unknown network %q",
+			sc.Network)
+	}
+	switch sc.Clock {
+	case "", StartClock, VirtualClock:
+	default:
+		return fmt.Errorf("invalid scenario: clock %q must be %s or %s",
+			sc.Clock, StartClock, VirtualClock)
+	}
+
+	for i, step := range sc.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("invalid scenario: steps[%d]: %v", i, err)
+		}
+		if i > 0 && step.At < sc.Steps[i-1].At {
+			return fmt.Errorf("invalid scenario: steps[%d]: at %v is "+
+				"before the previous step", i, step.At)
+		}
+	}
+	return nil
+}
+
+func (step ScenarioStep) validate() error {
+	if step.At < 0 {
+		return errors.New("at must be >= 0")
+	}
+
+	actions := 0
+	for _, set := range []bool{
+		step.Credit != nil,
+		step.Mine != 0,
+		step.Reorg != 0,
+		step.DoubleSpend != "",
+		step.Advance != 0,
+	} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("exactly one of credit, mine, reorg, " +
+			"doubleSpend and advance must be set")
+	}
+
+	switch {
+	case step.Credit != nil:
+		return step.Credit.validate()
+	case step.Mine < 0 || step.Mine > maxMineBlocks:
+		return fmt.Errorf("mine must be > 0 and <= %d", maxMineBlocks)
+	case step.Reorg < 0:
+		return errors.New("reorg must be > 0")
+	case step.Advance < 0:
+		return errors.New("advance must be > 0")
+	case step.DoubleSpend != "":
+		if _, err := chainhash.NewHashFromStr(step.DoubleSpend); err != nil {
+			return fmt.Errorf("invalid doubleSpend hash %q", step.DoubleSpend)
+		}
+	}
+	return nil
+}
+
+func (cr ScenarioCredit) validate() error {
+	if (cr.Address == "") == (cr.Account == "") {
+		return errors.New("exactly one of credit address and account must " +
+			"be set")
+	}
+	if cr.Value <= 0 {
+		return errors.New("credit value must be > 0")
+	}
+	if cr.Confirmations != nil && (*cr.Confirmations < 0 ||
+		*cr.Confirmations > maxCreditConfirmations) {
+		return fmt.Errorf("credit confirmations must be >= 0 and <= %d",
+			maxCreditConfirmations)
+	}
+	if cr.TxHash != "" {
+		if _, err := chainhash.NewHashFromStr(cr.TxHash); err != nil {
+			return fmt.Errorf("invalid credit txHash %q", cr.TxHash)
+		}
+	}
+	return nil
+}
+
+// Play plays the steps of sc against its network in the background. Credits
+// and blocks send hook events as they would if made through the /_mock/
+// endpoints. Steps that fail, such as a reorg deeper than the chain, are
+// logged and the scenario continues. It returns a function that stops the
+// scenario and waits for the step being played, if any, to finish.
+//
+// Credited addresses and accounts must exist when the scenario is played,
+// for example by loading them with the Seed option.
+func (s *Service) Play(sc Scenario) (func(), error) {
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
+	c := s.chain(sc.network())
+	for i, step := range sc.Steps {
+		if err := c.checkCredit(step.Credit); err != nil {
+			return nil, fmt.Errorf("invalid scenario: steps[%d]: %v", i, err)
+		}
+	}
+
+	start, _ := c.clock()
+	if sc.Clock != VirtualClock {
+		start = time.Now()
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.play(sc, start, stop)
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+		<-done
+	}, nil
+}
+
+// checkCredit returns an error if the address or account credited by cr, if
+// not nil, does not exist.
+func (c *chain) checkCredit(cr *ScenarioCredit) error {
+	switch {
+	case cr == nil:
+		return nil
+	case cr.Address != "":
+		if _, exists := c.AddressAccount(cr.Address); !exists {
+			return fmt.Errorf("address %s not found", cr.Address)
+		}
+	default:
+		if _, exists := c.AccountByLabel(cr.Account); !exists {
+			return fmt.Errorf("no account is labelled %q", cr.Account)
+		}
+	}
+	return nil
+}
+
+// play plays the steps of sc, timed from start, until they are done or stop
+// is closed.
+func (c *chain) play(sc Scenario, start time.Time, stop <-chan struct{}) {
+	for i, step := range sc.Steps {
+		for {
+			now, moved := c.clock()
+			if sc.Clock != VirtualClock {
+				now, moved = time.Now(), nil
+			}
+			wait := start.Add(step.At).Sub(now)
+			if wait <= 0 {
+				break
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-moved:
+				timer.Stop()
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err := c.playStep(step); err != nil {
+			log.Printf("Error playing scenario step %d at %v: %v.", i,
+				step.At, err)
+		}
+	}
+}
+
+func (c *chain) playStep(step ScenarioStep) error {
+	switch {
+	case step.Credit != nil:
+		return c.playCredit(*step.Credit)
+	case step.Mine > 0:
+		c.Mine(step.Mine)
+	case step.Reorg > 0:
+		_, err := c.Reorg(step.Reorg)
+		return err
+	case step.DoubleSpend != "":
+		_, err := c.DoubleSpend(step.DoubleSpend)
+		return err
+	case step.Advance > 0:
+		c.Advance(step.Advance)
+	}
+	return nil
+}
+
+func (c *chain) playCredit(cr ScenarioCredit) error {
+	addr := cr.Address
+	if addr == "" {
+		acc, exists := c.AccountByLabel(cr.Account)
+		if !exists {
+			return fmt.Errorf("no account is labelled %q", cr.Account)
+		}
+		var err error
+		if addr, err = c.accountAddress(acc.id); err != nil {
+			return err
+		}
+	}
+
+	in := incomingTx{
+		txHash:        cr.TxHash,
+		fromAddress:   cr.FromAddress,
+		confirmations: 1,
+		outputs:       []output{{address: addr, value: cr.Value}},
+	}
+	if cr.Confirmations != nil {
+		in.confirmations = *cr.Confirmations
+	}
+	_, err := c.Credit(in)
+	return err
+}
+
+// accountAddress returns the first, in lexical order, address of the account
+// accID, creating one if it has none.
+func (c *chain) accountAddress(accID int64) (string, error) {
+	c.mu.RLock()
+	first := ""
+	for addr, id := range c.addresses {
+		if id == accID && (first == "" || addr < first) {
+			first = addr
+		}
+	}
+	c.mu.RUnlock()
+
+	if first != "" {
+		return first, nil
+	}
+	return c.CreateAddress(accID)
+}
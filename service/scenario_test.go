@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtwire/mock/service"
+)
+
+// nextEvent returns the next event or fails the test if there is none.
+func nextEvent(t *testing.T, events <-chan service.Event) service.Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+	return service.Event{}
+}
+
+func TestPlayVirtual(t *testing.T) {
+	s := service.New()
+	acc := s.CreateAccount(service.MainNet, 0)
+	if err := s.SetLabel(service.MainNet, acc.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := service.ReadScenario(strings.NewReader(`
+clock: virtual
+steps:
+  - at: 1h
+    credit:
+      account: alice
+      value: 500
+      confirmations: 0
+  - at: 2h
+    mine: 1
+  - at: 3h
+    reorg: 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, cancel := s.Subscribe(service.MainNet)
+	defer cancel()
+
+	stop, err := s.Play(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	s.Advance(service.MainNet, time.Hour)
+	e := nextEvent(t, events)
+	if e.Type != service.TransactionEvent || e.Transaction.Value != 500 ||
+		e.Transaction.Status != "unconfirmed" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	s.Advance(service.MainNet, time.Hour)
+	if e := nextEvent(t, events); e.Type != service.BlockEvent {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if e := nextEvent(t, events); e.Type != service.TransactionEvent ||
+		e.Transaction.Status != "complete" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	s.Advance(service.MainNet, time.Hour)
+	if e := nextEvent(t, events); e.Type != service.TransactionEvent ||
+		e.Transaction.Status != "unconfirmed" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
+
+func TestPlayStart(t *testing.T) {
+	s := service.New()
+
+	events, cancel := s.Subscribe(service.MainNet)
+	defer cancel()
+
+	stop, err := s.Play(service.Scenario{
+		Steps: []service.ScenarioStep{
+			{At: 0, Mine: 1},
+			{At: 20 * time.Millisecond, Mine: 2},
+			{At: time.Hour, Mine: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if e := nextEvent(t, events); e.Type != service.BlockEvent ||
+			e.Block.Height != int64(i) {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	}
+
+	// Stopping returns without waiting for the last step.
+	stop()
+	stop()
+	if n := len(s.State(service.MainNet).Blocks); n != 3 {
+		t.Fatalf("expected 3 blocks got %d", n)
+	}
+}
+
+func TestScenarioInvalid(t *testing.T) {
+	s := service.New()
+
+	for _, file := range []string{
+		"network: regtest",
+		"clock: wall",
+		"steps: [{at: 1s}]",
+		"steps: [{at: 1s, mine: 1, reorg: 1}]",
+		"steps: [{at: 2s, mine: 1}, {at: 1s, mine: 1}]",
+		"steps: [{at: 1s, credit: {value: 1}}]",
+		"steps: [{at: 1s, credit: {account: a, value: 0}}]",
+		"steps: [{at: 1s, doubleSpend: xyz}]",
+		"steps: [{at: 1s, mine: 1, rate: 1}]",
+	} {
+		if _, err := service.ReadScenario(strings.NewReader(file)); err == nil {
+			t.Fatalf("expected error reading %q", file)
+		}
+	}
+
+	_, err := s.Play(service.Scenario{
+		Steps: []service.ScenarioStep{
+			{Credit: &service.ScenarioCredit{Account: "bob", Value: 1}},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"bob"`) {
+		t.Fatalf("expected account error got %v", err)
+	}
+}
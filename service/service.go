@@ -1,7 +1,7 @@
 package service
 
 import (
-	"errors"
+	crand "crypto/rand"
 	"math/rand"
 	"net/http"
 	"sync"
@@ -9,6 +9,7 @@ import (
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcutil"
 	"github.com/gorilla/mux"
 )
@@ -32,30 +33,108 @@ var networkParams = map[Network]*chaincfg.Params{
 type account struct {
 	id      int64
 	balance int64
+
+	// frozen is set when reversed credits leave the account with a negative
+	// balance. Frozen accounts can not send funds until their balance is
+	// restored.
+	frozen bool
+}
+
+// setBalance sets the balance of the account accID, unfreezing it once the
+// balance is no longer negative.
+func (c *chain) setBalance(accID, balance int64) {
+	acc := c.accounts[accID]
+	acc.balance = balance
+	acc.frozen = acc.frozen && balance < 0
+	c.accounts[accID] = acc
 }
 
 type transaction struct {
 	id int64
 	ty string
 
+	// status is "complete" for applied transactions and "unconfirmed" for
+	// credits that have not been mined. Double spent credits are
+	// "reversed". Transaction IDs that have not been used are reported as
+	// "pending", "expired" or "cancelled".
+	status string
+
 	fromAccountID int64
 	toAccountID   int64
 
+	// outputs are the addresses paid by a debit or the address receiving a
+	// credit.
+	outputs []output
+
 	value int64
 
+	// txHash, fee and fromAddress describe the simulated on-chain
+	// transaction of a debit or credit.
+	txHash      string
+	fee         int64
+	fromAddress string
+
+	// confirmations is the number of blocks confirming txHash when the
+	// transaction was read from the chain.
+	confirmations int64
+
 	created time.Time
 }
 
+type output struct {
+	address string
+	value   int64
+	txIndex int64
+}
+
+// vout returns the output of txHash paid by a credit. Credits injected
+// without an output pay the first.
+func (tx transaction) vout() int64 {
+	if len(tx.outputs) == 0 {
+		return 0
+	}
+	return tx.outputs[0].txIndex
+}
+
+// sameAs reports whether tx was requested with the same parameters as other.
+func (tx transaction) sameAs(other transaction) bool {
+	if len(tx.outputs) != len(other.outputs) {
+		return false
+	}
+	for i, out := range tx.outputs {
+		if out.address != other.outputs[i].address ||
+			out.value != other.outputs[i].value {
+			return false
+		}
+	}
+	return tx.ty == other.ty &&
+		tx.fromAccountID == other.fromAccountID &&
+		tx.toAccountID == other.toAccountID &&
+		tx.value == other.value
+}
+
+// incomingTx is an on-chain transaction paying addresses owned by the chain.
+type incomingTx struct {
+	txHash        string
+	fromAddress   string
+	confirmations int64
+	outputs       []output
+}
+
+type block struct {
+	height   int64
+	hash     string
+	txHashes []string
+}
+
 type fee struct {
 	feePerByte  int64
 	blockHeight int64
 }
 
-type chain struct {
-	mu sync.RWMutex
-
-	network Network
-
+// ledger is the state of a chain changed by its endpoints. It is copied to
+// take snapshots.
+type ledger struct {
 	accounts          map[int64]account
 	orderedAccountIDs []int64
 	accountLabels     map[string]int64
@@ -63,15 +142,104 @@ type chain struct {
 	addresses map[string]int64
 
 	transactions          map[int64]transaction
-	unusedTxIDs           map[int64]struct{}
+	unusedTxIDs           map[int64]time.Time
+	cancelledTxIDs        map[int64]struct{}
 	orderedTransactionIDs []int64
 
 	hooks map[string]struct{}
 
+	// blocks is the simulated chain of blocks confirming on-chain
+	// transactions. mempool holds those that have not been mined yet and
+	// txHeights the height of the block confirming each of the others.
+	blocks    []block
+	mempool   []string
+	txHeights map[string]int64
+
+	// txCredits maps on-chain transactions to the credits they created.
+	txCredits map[string][]int64
+
 	ids map[int64]struct{}
+}
+
+func newLedger() ledger {
+	return ledger{
+		accounts:      make(map[int64]account),
+		accountLabels: make(map[string]int64),
+
+		addresses: make(map[string]int64),
+
+		hooks: make(map[string]struct{}),
+
+		txHeights: make(map[string]int64),
+		txCredits: make(map[string][]int64),
+
+		transactions:   make(map[int64]transaction),
+		unusedTxIDs:    make(map[int64]time.Time),
+		cancelledTxIDs: make(map[int64]struct{}),
+
+		ids: make(map[int64]struct{}),
+	}
+}
+
+type chain struct {
+	mu sync.RWMutex
+
+	network Network
+
+	ledger
+
+	// fees is the fee table, which can be set by the Seed option.
+	fees []fee
+
+	// txIDExpiry is how long an unused transaction ID remains valid. Zero
+	// means forever.
+	txIDExpiry time.Duration
+
+	// clockOffset is added to the current time to give the chain's time.
+	clockOffset time.Duration
+
+	// clockMoved is closed when clockOffset changes.
+	clockMoved chan struct{}
+
+	faults       []*fault
+	rateLimiters []*rateLimiter
+	journal      []JournalEntry
+	stubs        []*Stub
+
+	// subscribers receive the events of the chain.
+	subscribers map[chan Event]struct{}
+
+	// initial, initialFaults and initialRateLimits hold the state of the
+	// chain when the service was created and are restored by Reset.
+	// snapshots hold those taken by Snapshot.
+	initial           ledger
+	initialFaults     []FaultRule
+	initialRateLimits []RateLimit
+	snapshots         map[int64]ledger
+	nextSnapshotID    int64
 
 	user string
 	pass string
+
+	// multiTenant is set by the Tenants option.
+	multiTenant bool
+
+	// strict is set by the Strict option.
+	strict bool
+
+	// validate is set by the Validate option.
+	validate bool
+
+	// credentials are those added by the Credentials option.
+	credentials []Credential
+
+	adminUser string
+	adminPass string
+}
+
+// now returns the time of the chain, which can be moved by Advance.
+func (c *chain) now() time.Time {
+	return time.Now().Add(c.clockOffset)
 }
 
 func (c *chain) params() *chaincfg.Params {
@@ -89,29 +257,48 @@ func (c *chain) nextID() int64 {
 	}
 }
 
-func (c *chain) CreateAccount() account {
+// CreateAccount creates an account with balance. No transaction is recorded
+// for the balance.
+func (c *chain) CreateAccount(balance int64) account {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.addAccount(c.nextID(), balance)
+}
+
+// addAccount adds the account id with balance. c.mu must be held.
+func (c *chain) addAccount(id, balance int64) account {
 	acc := account{
-		id:      c.nextID(),
-		balance: 0,
+		id:      id,
+		balance: balance,
+		frozen:  balance < 0,
 	}
 	c.accounts[acc.id] = acc
 	c.orderedAccountIDs = append(c.orderedAccountIDs, acc.id)
+	c.emitAccount(acc.id)
 	return acc
 }
 
-func (c *chain) Accounts(limit, next int) []account {
+func (c *chain) Accounts(limit, next int,
+	allows func(accID int64) bool) []account {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if next+limit > len(c.accounts) {
-		limit = len(c.accounts) - next
-	}
-	accs := make([]account, limit)
-	for i, id := range c.orderedAccountIDs[next : next+limit] {
-		accs[i] = c.accounts[id]
+	// Accounts that are not allowed are skipped before the window is taken
+	// so that pages stay full and next counts the accounts returned.
+	accs := []account{}
+	for _, id := range c.orderedAccountIDs {
+		if len(accs) >= limit {
+			break
+		}
+		if !allows(id) {
+			continue
+		}
+		if next > 0 {
+			next--
+			continue
+		}
+		accs = append(accs, c.accounts[id])
 	}
 	return accs
 }
@@ -125,19 +312,19 @@ func (c *chain) AccountTransactions(accID int64,
 	for _, id := range c.orderedTransactionIDs {
 		tx := c.transactions[id]
 		if tx.fromAccountID == accID || tx.toAccountID == accID {
-			txns = append(txns, tx)
+			txns = append(txns, c.withConfirmations(tx))
 		}
 	}
 	if next > len(txns) {
 		next = len(txns)
 	}
 	if next+limit > len(txns) {
-		limit = next
+		limit = len(txns) - next
 	}
 	return txns[next : next+limit]
 }
 
-var errAccountNotFound = errors.New("account not found")
+var errAccountNotFound = newError("account_not_found", "account not found")
 
 func (c *chain) CreateAddress(accountID int64) (string, error) {
 	c.mu.Lock()
@@ -167,6 +354,14 @@ func (c *chain) CreateAddress(accountID int64) (string, error) {
 	return addr, nil
 }
 
+// AddressAccount returns the ID of the account owning address.
+func (c *chain) AddressAccount(address string) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	accID, exists := c.addresses[address]
+	return accID, exists
+}
+
 func (c *chain) Account(id int64) (account, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -182,31 +377,317 @@ func (c *chain) AccountByLabel(label string) (account, bool) {
 	return acc, exists
 }
 
-func (c *chain) Credit(address string, value int64) (int64, bool) {
+var errLabelExists = newError("label_exists", "label exists")
+
+// SetLabel replaces the label of the account accID with label. An empty label
+// removes it.
+func (c *chain) SetLabel(accID int64, label string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	accID, exists := c.addresses[address]
-	if !exists {
-		return 0, false
+	if _, exists := c.accounts[accID]; !exists {
+		return errAccountNotFound
 	}
+	if id, exists := c.accountLabels[label]; exists && id != accID {
+		return errLabelExists
+	}
+	for l, id := range c.accountLabels {
+		if id == accID {
+			delete(c.accountLabels, l)
+		}
+	}
+	if label != "" {
+		c.accountLabels[label] = accID
+	}
+	return nil
+}
 
-	acc := c.accounts[accID]
-	acc.balance += value
-	c.accounts[accID] = acc
+var (
+	errAddressNotFound = newError("address_not_found", "address not found")
+	errNoOutputs       = newError("no_outputs", "no outputs")
+	errOutputCredited  = newError("output_credited",
+		"output already credited")
+	errInvalidConfirmations = errorf("invalid_confirmations",
+		"confirmations must be >= 0 and <= %d", maxCreditConfirmations)
+)
 
+// Credit credits the accounts owning the outputs of the on-chain transaction
+// in. The credits are applied, and hooks sent them, once in has at least one
+// confirmation. It returns the IDs of the credit transactions created.
+func (c *chain) Credit(in incomingTx) ([]int64, error) {
+	txIDs, confirmedTxIDs, err := c.credit(in)
+	if err != nil {
+		return nil, err
+	}
+	c.sendHookCreditEvents(confirmedTxIDs)
+	return txIDs, nil
+}
+
+// credit adds the credits of in and returns their IDs and the IDs of those
+// confirmed by it.
+func (c *chain) credit(in incomingTx) ([]int64, []int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(in.outputs) == 0 {
+		return nil, nil, errNoOutputs
+	}
+	if in.confirmations < 0 || in.confirmations > maxCreditConfirmations {
+		return nil, nil, errInvalidConfirmations
+	}
+
+	if in.txHash == "" {
+		in.txHash = randomHash()
+	}
+
+	vouts := make(map[int64]bool)
+	for _, out := range in.outputs {
+		if _, exists := c.addresses[out.address]; !exists {
+			return nil, nil, errAddressNotFound
+		}
+		if out.value <= 0 {
+			return nil, nil, errInvalidValue
+		}
+		if vouts[out.txIndex] || c.outputCredited(in.txHash, out.txIndex) {
+			return nil, nil, errOutputCredited
+		}
+		vouts[out.txIndex] = true
+	}
+
+	txIDs := make([]int64, len(in.outputs))
+	for i, out := range in.outputs {
+		txIDs[i] = c.addCredit(in, out)
+	}
+
+	// Further outputs of a transaction already in a block are confirmed by
+	// it.
+	if _, mined := c.txHeights[in.txHash]; mined {
+		return txIDs, c.confirmCredits(in.txHash), nil
+	}
+	c.mempool = removeString(c.mempool, in.txHash)
+
+	if in.confirmations == 0 {
+		c.mempool = append(c.mempool, in.txHash)
+		return txIDs, nil, nil
+	}
+
+	confirmedTxIDs := c.mine(in.txHash)
+	for i := int64(1); i < in.confirmations; i++ {
+		c.mine()
+	}
+	return txIDs, confirmedTxIDs, nil
+}
+
+// outputCredited reports whether the output vout of the on-chain transaction
+// txHash has been credited.
+func (c *chain) outputCredited(txHash string, vout int64) bool {
+	for _, txID := range c.txCredits[txHash] {
+		if c.transactions[txID].vout() == vout {
+			return true
+		}
+	}
+	return false
+}
+
+// addCredit adds an unconfirmed credit for the output out of the on-chain
+// transaction in and returns its ID. The credit is applied to the account
+// owning out.address when in is mined.
+func (c *chain) addCredit(in incomingTx, out output) int64 {
 	txID := c.nextID()
 	tx := transaction{
 		id:          txID,
 		ty:          "credit",
-		toAccountID: accID,
-		value:       value,
-		created:     time.Now(),
+		status:      "unconfirmed",
+		toAccountID: c.addresses[out.address],
+		outputs:     []output{out},
+		value:       out.value,
+		txHash:      in.txHash,
+		fromAddress: in.fromAddress,
+		created:     c.now(),
 	}
 	c.transactions[txID] = tx
 	c.orderedTransactionIDs = append(c.orderedTransactionIDs, txID)
+	c.txCredits[in.txHash] = append(c.txCredits[in.txHash], txID)
+	c.emitTransaction(txID)
+
+	return txID
+}
+
+// Mine mines n blocks, the first of which confirms every transaction in the
+// mempool, and returns them.
+func (c *chain) Mine(n int) []block {
+	blocks, creditTxIDs := c.mineBlocks(n)
+	c.sendHookCreditEvents(creditTxIDs)
+	return blocks
+}
+
+func (c *chain) mineBlocks(n int) ([]block, []int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	creditTxIDs := []int64{}
+	for i := 0; i < n; i++ {
+		creditTxIDs = append(creditTxIDs, c.mine(c.mempool...)...)
+		c.mempool = nil
+	}
+	return c.blocks[len(c.blocks)-n:], creditTxIDs
+}
+
+// mine appends a block confirming the on-chain transactions txHashes to the
+// simulated chain. Any credits the transactions pay are applied and their
+// IDs returned.
+func (c *chain) mine(txHashes ...string) []int64 {
+	b := block{
+		height:   int64(len(c.blocks)),
+		hash:     randomHash(),
+		txHashes: txHashes,
+	}
+	c.blocks = append(c.blocks, b)
+	c.emitBlock(b)
+
+	creditTxIDs := []int64{}
+	for _, txHash := range txHashes {
+		c.txHeights[txHash] = b.height
+		creditTxIDs = append(creditTxIDs, c.confirmCredits(txHash)...)
+	}
+	return creditTxIDs
+}
+
+// confirmCredits applies the unconfirmed credits paid by txHash and returns
+// their IDs. c.mu must be held.
+func (c *chain) confirmCredits(txHash string) []int64 {
+	txIDs := []int64{}
+	for _, txID := range c.txCredits[txHash] {
+		tx := c.transactions[txID]
+		if tx.status != "unconfirmed" {
+			continue
+		}
+		tx.status = "complete"
+		c.transactions[txID] = tx
+
+		c.setBalance(tx.toAccountID,
+			c.accounts[tx.toAccountID].balance+tx.value)
+		c.emitTransaction(txID)
+
+		txIDs = append(txIDs, txID)
+	}
+	return txIDs
+}
+
+var (
+	errInvalidDepth   = newError("invalid_depth", "invalid depth")
+	errCreditNotFound = newError("credit_not_found", "credit not found")
+)
+
+// Reorg removes the last depth blocks from the chain. Their transactions
+// return to the mempool and any credits they applied are reversed until they
+// are mined again. It returns the IDs of the reversed credits.
+func (c *chain) Reorg(depth int) ([]int64, error) {
+	txIDs, err := c.reorg(depth)
+	if err != nil {
+		return nil, err
+	}
+	c.sendHookCreditEvents(txIDs)
+	return txIDs, nil
+}
+
+func (c *chain) reorg(depth int) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if depth < 1 || depth > len(c.blocks) {
+		return nil, errInvalidDepth
+	}
+
+	removed := c.blocks[len(c.blocks)-depth:]
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+
+	txIDs := []int64{}
+	for _, b := range removed {
+		for _, txHash := range b.txHashes {
+			delete(c.txHeights, txHash)
+			c.mempool = append(c.mempool, txHash)
+			txIDs = append(txIDs, c.reverseCredits(txHash, "unconfirmed")...)
+		}
+	}
+	return txIDs, nil
+}
+
+// DoubleSpend simulates the on-chain transaction txHash being replaced by a
+// conflicting transaction. The transaction is removed from the chain and its
+// credits are permanently reversed. It returns the IDs of the reversed
+// credits.
+func (c *chain) DoubleSpend(txHash string) ([]int64, error) {
+	txIDs, err := c.doubleSpend(txHash)
+	if err != nil {
+		return nil, err
+	}
+	c.sendHookCreditEvents(txIDs)
+	return txIDs, nil
+}
+
+func (c *chain) doubleSpend(txHash string) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.txCredits[txHash]; !exists {
+		return nil, errCreditNotFound
+	}
+
+	if height, exists := c.txHeights[txHash]; exists {
+		b := c.blocks[height]
+		b.txHashes = removeString(b.txHashes, txHash)
+		c.blocks[height] = b
+		delete(c.txHeights, txHash)
+	}
+	c.mempool = removeString(c.mempool, txHash)
+
+	return c.reverseCredits(txHash, "reversed"), nil
+}
+
+// reverseCredits sets the status of the credits paid by txHash and removes
+// any value they added to account balances. Accounts left with a negative
+// balance are frozen. It returns the IDs of the credits that changed.
+func (c *chain) reverseCredits(txHash, status string) []int64 {
+	txIDs := []int64{}
+	for _, txID := range c.txCredits[txHash] {
+		tx := c.transactions[txID]
+		if tx.status == status || tx.status == "reversed" {
+			continue
+		}
 
-	return txID, true
+		if tx.status == "complete" {
+			acc := c.accounts[tx.toAccountID]
+			acc.balance -= tx.value
+			acc.frozen = acc.frozen || acc.balance < 0
+			c.accounts[tx.toAccountID] = acc
+		}
+
+		tx.status = status
+		c.transactions[txID] = tx
+		c.emitTransaction(txID)
+		txIDs = append(txIDs, txID)
+	}
+	return txIDs
+}
+
+func removeString(strs []string, str string) []string {
+	kept := []string{}
+	for _, s := range strs {
+		if s != str {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// withConfirmations returns tx with the number of blocks confirming its
+// on-chain transaction set.
+func (c *chain) withConfirmations(tx transaction) transaction {
+	if height, exists := c.txHeights[tx.txHash]; exists {
+		tx.confirmations = int64(len(c.blocks)) - height
+	}
+	return tx
 }
 
 func (c *chain) CreateTransactionID() int64 {
@@ -214,127 +695,419 @@ func (c *chain) CreateTransactionID() int64 {
 	defer c.mu.Unlock()
 
 	id := c.nextID()
-	c.unusedTxIDs[id] = struct{}{}
+	c.unusedTxIDs[id] = c.now()
 	return id
 }
 
+func (c *chain) txIDExpired(created time.Time) bool {
+	return c.txIDExpiry > 0 && c.now().Sub(created) > c.txIDExpiry
+}
+
 func (c *chain) Transaction(id int64) (transaction, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	if tx, exists := c.transactions[id]; exists {
-		return tx, true
+		return c.withConfirmations(tx), true
 	}
 
-	if _, exists := c.unusedTxIDs[id]; exists {
+	if created, exists := c.unusedTxIDs[id]; exists {
+		status := "pending"
+		if c.txIDExpired(created) {
+			status = "expired"
+		}
 		return transaction{
-			id: id,
+			id:      id,
+			status:  status,
+			created: created,
 		}, true
+	}
 
+	if _, exists := c.cancelledTxIDs[id]; exists {
+		return transaction{
+			id:     id,
+			status: "cancelled",
+		}, true
 	}
 	return transaction{}, false
 }
 
-func (c *chain) Transfer(txID, fromAccID, toAccID, value int64) error {
+var (
+	errTxIDUsed      = newError("tx_id_used", "txID already used")
+	errTxIDExpired   = newError("tx_id_expired", "txID expired")
+	errTxIDCancelled = newError("tx_id_cancelled", "txID cancelled")
+	errTxNotFound    = newError("transaction_not_found",
+		"transaction not found")
+)
+
+// CancelTransactionID stops the unused transaction ID id from being used.
+// Cancelling an ID more than once has no effect.
+func (c *chain) CancelTransactionID(id int64) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exists := c.unusedTxIDs[txID]; !exists {
-		return errors.New("invalid txID")
+	if _, exists := c.transactions[id]; exists {
+		return errTxIDUsed
 	}
-
-	fromAcc, exists := c.accounts[fromAccID]
-	if !exists {
-		return errors.New("no from account")
+	if _, exists := c.cancelledTxIDs[id]; exists {
+		return nil
 	}
-
-	toAcc, exists := c.accounts[toAccID]
-	if !exists {
-		return errors.New("no to account")
+	if _, exists := c.unusedTxIDs[id]; !exists {
+		return errTxNotFound
 	}
+	delete(c.unusedTxIDs, id)
+	c.cancelledTxIDs[id] = struct{}{}
+	return nil
+}
 
-	if value <= 0 {
-		return errors.New("invalid balance")
-	}
+var (
+	errAccountFrozen = newError("account_frozen", "account frozen")
+	errInvalidTxID   = newError("invalid_tx_id", "invalid txID")
+	errTxIDConflict  = newError("tx_id_conflict",
+		"txID used with different parameters")
+
+	errInvalidType   = newError("invalid_type", "invalid type")
+	errInvalidValue  = newError("invalid_value", "invalid value")
+	errValueNotTotal = newError("invalid_value",
+		"value is not the total of the outputs")
+	errNoToAddress = newError("invalid_address", "no to address")
+
+	errNoFromAccount     = newError("account_not_found", "no from account")
+	errNoToAccount       = newError("account_not_found", "no to account")
+	errInsufficientFunds = newError("insufficient_funds",
+		"insufficient funds")
+)
 
-	if fromAcc.balance < value {
-		return errors.New("insufficient funds")
+// replayed reports whether tx.id has already been used. A resubmission with
+// parameters identical to the original is not an error so that clients can
+// safely retry.
+func (c *chain) replayed(tx transaction) (bool, error) {
+	orig, exists := c.transactions[tx.id]
+	if !exists {
+		return false, nil
 	}
+	if !orig.sameAs(tx) {
+		return true, errTxIDConflict
+	}
+	return true, nil
+}
 
-	fromAcc.balance = fromAcc.balance - value
-	toAcc.balance = toAcc.balance + value
-
-	c.accounts[fromAccID] = fromAcc
-	c.accounts[toAccID] = toAcc
-
-	c.transactions[txID] = transaction{
+func (c *chain) Transfer(txID, fromAccID, toAccID, value int64) error {
+	return firstError(c.Transactions([]transaction{{
 		id:            txID,
 		ty:            "transfer",
 		fromAccountID: fromAccID,
 		toAccountID:   toAccID,
 		value:         value,
-		created:       time.Now(),
-	}
-	c.orderedTransactionIDs = append(c.orderedTransactionIDs, txID)
-	delete(c.unusedTxIDs, txID)
-
-	return nil
+	}}))
 }
 
 func (c *chain) Debit(txID, fromAccID int64,
 	toAddr string, value int64) error {
+	return firstError(c.Transactions([]transaction{{
+		id:            txID,
+		ty:            "debit",
+		fromAccountID: fromAccID,
+		outputs: []output{
+			{address: toAddr, value: value},
+		},
+		value: value,
+	}}))
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InjectTransaction records the complete transfer, debit or credit tx and
+// applies it to the balances of its accounts. Unlike Transactions, tx does not
+// need a transaction ID created by CreateTransactionID, funds are not checked
+// and no fees are charged, so that past transactions can be recreated. tx is
+// given an ID if tx.id is 0 and the current time if tx.created is zero. It
+// returns the transaction recorded.
+func (c *chain) InjectTransaction(tx transaction) (transaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.inject(tx)
+}
+
+// inject records tx as described by InjectTransaction. c.mu must be held.
+func (c *chain) inject(tx transaction) (transaction, error) {
+	if tx.value <= 0 {
+		return transaction{}, errInvalidValue
+	}
+
+	switch tx.ty {
+	case "transfer", "debit":
+		if _, exists := c.accounts[tx.fromAccountID]; !exists {
+			return transaction{}, errNoFromAccount
+		}
+	case "credit":
+	default:
+		return transaction{}, errInvalidType
+	}
+	switch tx.ty {
+	case "transfer", "credit":
+		if _, exists := c.accounts[tx.toAccountID]; !exists {
+			return transaction{}, errNoToAccount
+		}
+	}
+	if tx.ty == "debit" {
+		if len(tx.outputs) == 0 {
+			return transaction{}, errNoOutputs
+		}
+		total := int64(0)
+		for _, out := range tx.outputs {
+			total += out.value
+		}
+		if total != tx.value {
+			return transaction{}, errValueNotTotal
+		}
+	}
+	if tx.ty != "transfer" {
+		if tx.txHash == "" {
+			tx.txHash = randomHash()
+		} else if tx.ty == "credit" && c.outputCredited(tx.txHash, tx.vout()) {
+			return transaction{}, errOutputCredited
+		}
+	}
+
+	if tx.id == 0 {
+		tx.id = c.nextID()
+	} else if _, exists := c.ids[tx.id]; exists {
+		return transaction{}, errInvalidTxID
+	} else {
+		c.ids[tx.id] = struct{}{}
+	}
+	if tx.created.IsZero() {
+		tx.created = c.now()
+	}
+	tx.status = "complete"
+
+	if tx.ty != "credit" {
+		c.setBalance(tx.fromAccountID,
+			c.accounts[tx.fromAccountID].balance-tx.value)
+	}
+	if tx.ty != "debit" {
+		c.setBalance(tx.toAccountID,
+			c.accounts[tx.toAccountID].balance+tx.value)
+	}
+	if tx.ty == "credit" {
+		c.txCredits[tx.txHash] = append(c.txCredits[tx.txHash], tx.id)
+	}
+
+	c.transactions[tx.id] = tx
+	c.orderedTransactionIDs = append(c.orderedTransactionIDs, tx.id)
+	c.emitTransaction(tx.id)
+	return tx, nil
+}
+
+// Transactions atomically applies the transfers and debits in txns. Either
+// all of them are applied and nil is returned or none are and the returned
+// slice holds the error, if any, for each transaction in txns.
+//
+// Debit outputs paying addresses owned by the chain are credited to their
+// accounts once the debit is confirmed.
+func (c *chain) Transactions(txns []transaction) []error {
+	errs, creditTxIDs := c.applyTransactions(txns)
+	c.sendHookCreditEvents(creditTxIDs)
+	return errs
+}
+
+func (c *chain) applyTransactions(txns []transaction) ([]error, []int64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exists := c.unusedTxIDs[txID]; !exists {
-		return errors.New("invalid txID")
+	errs := make([]error, len(txns))
+	failed := false
+
+	// Balances are tracked separately until every transaction is known to
+	// succeed.
+	balances := make(map[int64]int64)
+	ids := make(map[int64]bool)
+	for i, tx := range txns {
+		if ids[tx.id] {
+			errs[i], failed = errInvalidTxID, true
+			continue
+		}
+		ids[tx.id] = true
+
+		if err := c.checkTransaction(tx, balances); err != nil {
+			errs[i], failed = err, true
+		}
+	}
+	if failed {
+		return errs, nil
 	}
 
-	fromAcc, exists := c.accounts[fromAccID]
+	now := c.now()
+	debits := []transaction{}
+	applied := []int64{}
+	for _, tx := range txns {
+		if _, exists := c.transactions[tx.id]; exists {
+			continue
+		}
+		tx.created = now
+		tx.status = "complete"
+		if tx.ty == "debit" {
+			tx.txHash = randomHash()
+			tx.fee = c.debitFee(len(tx.outputs))
+			outputs := make([]output, len(tx.outputs))
+			for i, out := range tx.outputs {
+				out.txIndex = int64(i)
+				outputs[i] = out
+			}
+			tx.outputs = outputs
+			debits = append(debits, tx)
+		}
+		c.transactions[tx.id] = tx
+		c.orderedTransactionIDs = append(c.orderedTransactionIDs, tx.id)
+		delete(c.unusedTxIDs, tx.id)
+		applied = append(applied, tx.id)
+	}
+	for accID, balance := range balances {
+		c.setBalance(accID, balance)
+	}
+	for _, txID := range applied {
+		c.emitTransaction(txID)
+	}
+
+	if len(debits) == 0 {
+		return nil, nil
+	}
+
+	txHashes := make([]string, len(debits))
+	for i, tx := range debits {
+		txHashes[i] = tx.txHash
+		for _, out := range tx.outputs {
+			if _, exists := c.addresses[out.address]; exists {
+				c.addCredit(incomingTx{txHash: tx.txHash}, out)
+			}
+		}
+	}
+	return nil, c.mine(txHashes...)
+}
+
+// checkTransaction validates tx against the chain and updates balances with
+// the resulting account balances.
+func (c *chain) checkTransaction(tx transaction,
+	balances map[int64]int64) error {
+
+	if replayed, err := c.replayed(tx); replayed {
+		return err
+	}
+
+	if _, exists := c.cancelledTxIDs[tx.id]; exists {
+		return errTxIDCancelled
+	}
+
+	created, exists := c.unusedTxIDs[tx.id]
 	if !exists {
-		return errors.New("no from account")
+		return errInvalidTxID
+	}
+	if c.txIDExpired(created) {
+		return errTxIDExpired
 	}
 
-	if toAddr == "" {
-		return errors.New("no to address")
+	fromAcc, exists := c.accounts[tx.fromAccountID]
+	if !exists {
+		return errNoFromAccount
+	}
+	if fromAcc.frozen {
+		return errAccountFrozen
 	}
 
-	if value <= 0 {
-		return errors.New("invalid balance")
+	switch tx.ty {
+	case "transfer":
+		if _, exists := c.accounts[tx.toAccountID]; !exists {
+			return errNoToAccount
+		}
+	case "debit":
+		if len(tx.outputs) == 0 {
+			return errNoOutputs
+		}
+		total := int64(0)
+		for _, out := range tx.outputs {
+			if out.address == "" {
+				return errNoToAddress
+			}
+			if out.value <= 0 {
+				return errInvalidValue
+			}
+			total += out.value
+		}
+		if total != tx.value {
+			return errValueNotTotal
+		}
+	default:
+		return errInvalidType
 	}
 
-	if fromAcc.balance < value {
-		return errors.New("insufficient funds")
+	if tx.value <= 0 {
+		return errInvalidValue
 	}
 
-	fromAcc.balance = fromAcc.balance - value
-	c.accounts[fromAccID] = fromAcc
+	balance := func(accID int64) int64 {
+		if b, exists := balances[accID]; exists {
+			return b
+		}
+		return c.accounts[accID].balance
+	}
 
-	c.transactions[txID] = transaction{
-		id:            txID,
-		ty:            "debit",
-		fromAccountID: fromAccID,
-		value:         value,
-		created:       time.Now(),
+	if balance(tx.fromAccountID) < tx.value {
+		return errInsufficientFunds
 	}
-	c.orderedTransactionIDs = append(c.orderedTransactionIDs, txID)
-	delete(c.unusedTxIDs, txID)
 
+	balances[tx.fromAccountID] = balance(tx.fromAccountID) - tx.value
+	switch tx.ty {
+	case "transfer":
+		balances[tx.toAccountID] = balance(tx.toAccountID) + tx.value
+	case "debit":
+		// The network fee is paid from the fee account, which is allowed to
+		// go negative.
+		feeAccID := c.accountLabels["_fee"]
+		balances[feeAccID] = balance(feeAccID) - c.debitFee(len(tx.outputs))
+	}
 	return nil
 }
 
-func (c *chain) Fees() []fee {
-	return []fee{
-		{
-			feePerByte:  100,
-			blockHeight: 451000,
-		},
+// debitFee returns the network fee of a simulated on-chain transaction with
+// one input and n outputs.
+func (c *chain) debitFee(n int) int64 {
+	const (
+		baseSize   = 10
+		inputSize  = 148
+		outputSize = 34
+	)
+	size := int64(baseSize + inputSize + n*outputSize)
+	return size * c.Fees()[0].feePerByte
+}
+
+// randomHash returns a random hex encoded hash in the format of a Bitcoin
+// transaction or block hash.
+func randomHash() string {
+	var h chainhash.Hash
+	if _, err := crand.Read(h[:]); err != nil {
+		panic(err)
 	}
+	return h.String()
+}
+
+// Fees returns the fee table of the chain, the first of which is charged for
+// debits.
+func (c *chain) Fees() []fee {
+	return c.fees
 }
 
 var (
-	errHookExists = errors.New("hook exists")
-	errMaxHooks   = errors.New("max hooks")
+	errHookExists = newError("hook_exists", "hook exists")
+	errMaxHooks   = newError("max_hooks", "max hooks reached")
 )
 
 func (c *chain) CreateHook(url string) error {
@@ -374,39 +1147,53 @@ func (c *chain) DeleteHook(url string) bool {
 	return true
 }
 
-type service struct {
+// Service is a mock RTWire service created by New. Its methods taking a
+// Network panic if it is not MainNet or TestNet3, as passing another is a
+// programming error rather than a failure of the mock.
+type Service struct {
 	router *mux.Router
+	chains map[Network]*chain
 }
 
-// New returns a high fidelity mock RTWire service. The *service struct
+// chain returns the chain of network. It panics if network is not MainNet or
+// TestNet3.
+func (s *Service) chain(network Network) *chain {
+	c, exists := s.chains[network]
+	if !exists {
+		panic("unknown network " + string(network))
+	}
+	return c
+}
+
+// New returns a high fidelity mock RTWire service. The *Service struct
 // implements http.Handler and exposes HTTP endpoints identical to those
-// described in https://rtwire.com/docs. This means *service can be combined
+// described in https://rtwire.com/docs. This means *Service can be combined
 // httptest.Server to unit test your RTWire integration code locally. For
 // example:
 //
-//    s := httptest.NewServer(cervice.New())
-//    defer s.Close()
+//	s := httptest.NewServer(cervice.New())
+//	defer s.Close()
 //
-//    req, err := http.NewRequest("GET", s.URL+"/v1/mainnet/accounts", nil)
-//    req.SetBasicAuth("user", "pass")
-//    req.Header.Set("Content-Type", "application/json")
+//	req, err := http.NewRequest("GET", s.URL+"/v1/mainnet/accounts", nil)
+//	req.SetBasicAuth("user", "pass")
+//	req.Header.Set("Content-Type", "application/json")
 //
-//    resp, err := http.DefaultClient.Do(req)
-//    // Handle error if not nil. resp.Body will contain a JSON object with all
-//    // accounts created so far with the mock service.
+//	resp, err := http.DefaultClient.Do(req)
+//	// Handle error if not nil. resp.Body will contain a JSON object with all
+//	// accounts created so far with the mock service.
 //
 // This will allow the s.URL to expose the same HTTP endpoints as RTWire's. See
 // https://github.com/rtwire/go/client/ for examples. Importantly this mock
 // service exposes one extra endpoint:
 //
-//     POST /v1/[network]/addresses/[address]
+//	POST /v1/[network]/addresses/[address]
 //
 // This endpoint can be passed the folliwng JSON object that will credit the
 // account at address [address] with value [value].
 //
-//    {
-//      "value": [value]
-//	  }
+//	   {
+//	     "value": [value]
+//		  }
 //
 // Note that you must specify a "Content-Type: application/json" header with
 // this endpoint. Using this endpoint is the equivalent to receiving bitcoins
@@ -414,51 +1201,85 @@ type service struct {
 //
 // The default basic authentication username is user is 'user' and password is
 // 'pass'. Both can be changed by using the UserPass option.
-func New(options ...option) *service {
-	s := &service{
-		router: mux.NewRouter().PathPrefix("/v1").Subrouter(),
+//
+// Operations used to orchestrate tests, such as mining blocks, resetting the
+// service or injecting faults, are found under /_mock/[network]/ and use
+// their own credentials, 'admin' and 'pass' by default, which can be changed
+// by using the AdminUserPass option.
+//
+// The state of each network can also be changed, read and watched directly
+// from Go with the methods of *Service, such as CreateAccount, Credit,
+// InjectTransaction, State and Subscribe, which are much faster than the
+// equivalent HTTP requests.
+func New(options ...Option) *Service {
+	s := &Service{
+		router: mux.NewRouter(),
+		chains: make(map[Network]*chain),
 	}
+	s.router.NotFoundHandler = http.HandlerFunc(routeNotFoundHandler)
+	s.router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
 
 	for _, net := range []Network{TestNet3, MainNet} {
-		c := &chain{
-			network: net,
+		c := newChain(net, options)
+		ts := newTenants(c, options)
 
-			accounts:      make(map[int64]account),
-			accountLabels: make(map[string]int64),
+		name := c.params().Name
+		s.router.PathPrefix("/v1/" + name).Handler(ts.handler())
+		s.router.PathPrefix("/_mock/" + name).Handler(ts.adminHandler())
 
-			addresses: make(map[string]int64),
+		s.chains[net] = c
+	}
+	return s
+}
 
-			hooks: make(map[string]struct{}),
+// newChain returns a chain for network with options applied.
+func newChain(network Network, options []Option) *chain {
+	c := &chain{
+		network: network,
 
-			transactions: make(map[int64]transaction),
-			unusedTxIDs:  make(map[int64]struct{}),
+		ledger: newLedger(),
 
-			ids: make(map[int64]struct{}),
+		snapshots: make(map[int64]ledger),
 
-			user: "user",
-			pass: "pass",
-		}
+		clockMoved: make(chan struct{}),
 
-		for _, op := range options {
-			op(c)
-		}
+		fees: []fee{
+			{
+				feePerByte:  100,
+				blockHeight: 451000,
+			},
+		},
 
-		// All client accounts begin with an account where service fees can be
-		// sent and deducted.
-		feeAcc := c.CreateAccount()
-		c.accountLabels["_fee"] = feeAcc.id
+		user: "user",
+		pass: "pass",
 
-		c.handler(s.router.PathPrefix("/" + c.params().Name).Subrouter())
+		adminUser: "admin",
+		adminPass: "pass",
+	}
 
+	for _, op := range options {
+		op(c)
 	}
-	return s
+
+	// All client accounts begin with an account where service fees can be
+	// sent and deducted, unless one was loaded by the Seed option.
+	if _, exists := c.accountLabels["_fee"]; !exists {
+		feeAcc := c.CreateAccount(0)
+		c.accountLabels["_fee"] = feeAcc.id
+	}
+
+	c.initial = c.ledger.copy()
+	c.initialFaults = c.Faults()
+	c.initialRateLimits = c.RateLimits()
+	return c
 }
 
-type option func(*chain)
+// Option configures a Service created by New.
+type Option func(*chain)
 
 // UserPass is an option that can be passsed to New() to change the default user
 // and pass authentication credentials for the specified network.
-func UserPass(network Network, user, pass string) option {
+func UserPass(network Network, user, pass string) Option {
 	return func(c *chain) {
 		if c.network == network {
 			c.user = user
@@ -467,6 +1288,61 @@ func UserPass(network Network, user, pass string) option {
 	}
 }
 
-func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// AdminUserPass is an option that can be passed to New() to change the default
+// user and pass authentication credentials of the /_mock/[network]/ endpoints
+// for the specified network.
+func AdminUserPass(network Network, user, pass string) Option {
+	return func(c *chain) {
+		if c.network == network {
+			c.adminUser = user
+			c.adminPass = pass
+		}
+	}
+}
+
+// Tenants is an option that can be passed to New() to isolate clients of the
+// specified network from each other. Each tenant is served by its own
+// independent accounts, transactions, hooks, blocks, faults, stubs and
+// journal, created on first use. The tenant of a request is given by its
+// X-Mock-Tenant header or, if the header is not set, its basic authentication
+// user name. Any user name is accepted with the network's password.
+//
+// Requests without a tenant, or whose tenant is the network's user name, are
+// served by the default tenant which is also used by the methods of
+// *Service.
+func Tenants(network Network) Option {
+	return func(c *chain) {
+		if c.network == network {
+			c.multiTenant = true
+		}
+	}
+}
+
+// TxIDExpiry is an option that can be passed to New() to make transaction IDs
+// created with POST /transactions/ expire if unused after d on the specified
+// network. By default they never expire.
+func TxIDExpiry(network Network, d time.Duration) Option {
+	return func(c *chain) {
+		if c.network == network {
+			c.txIDExpiry = d
+		}
+	}
+}
+
+// Faults is an option that can be passed to New() to inject faults into
+// requests to the specified network. It panics if a rule is invalid. Rules
+// can also be changed while the service is running using the
+// /_mock/[network]/faults/ endpoint.
+func Faults(network Network, rules ...FaultRule) Option {
+	return func(c *chain) {
+		if c.network == network {
+			if err := c.SetFaults(rules); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
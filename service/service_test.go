@@ -0,0 +1,146 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve sends a JSON encoded body, if not nil, to h with the default
+// credentials and headers set and returns the recorded response.
+func serve(h http.Handler, method, url string,
+	body interface{}) *httptest.ResponseRecorder {
+	return serveAs(h, "user", "pass", method, url, body)
+}
+
+// serveAdmin is serve with the default credentials of the /_mock/ endpoints.
+func serveAdmin(h http.Handler, method, url string,
+	body interface{}) *httptest.ResponseRecorder {
+	return serveAs(h, "admin", "pass", method, url, body)
+}
+
+func serveAs(h http.Handler, user, pass, method, url string,
+	body interface{}) *httptest.ResponseRecorder {
+
+	var req io.Reader
+	if body != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			panic(err)
+		}
+		req = &buf
+	}
+
+	r := httptest.NewRequest(method, url, req)
+	r.SetBasicAuth(user, pass)
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func expectCode(t *testing.T, w *httptest.ResponseRecorder, code int) {
+	if w.Code != code {
+		t.Fatalf("expected %v got %v: %v", code, w.Code, w.Body.String())
+	}
+}
+
+func createAccount(t *testing.T, h http.Handler) int64 {
+	w := serve(h, "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].ID
+}
+
+func createAddress(t *testing.T, h http.Handler, accID int64) string {
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d/addresses/", accID)
+	w := serve(h, "POST", url, nil)
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			Address string
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].Address
+}
+
+// credit credits addr with value and returns the credit transaction ID.
+func credit(t *testing.T, h http.Handler, addr string, value int64) int64 {
+	url := fmt.Sprintf("/v1/mainnet/addresses/%s", addr)
+	w := serve(h, "POST", url, struct {
+		Value int64 `json:"value"`
+	}{
+		Value: value,
+	})
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].ID
+}
+
+// fundedAccount creates an account with an address credited with value.
+func fundedAccount(t *testing.T, h http.Handler, value int64) int64 {
+	accID := createAccount(t, h)
+	credit(t, h, createAddress(t, h, accID), value)
+	return accID
+}
+
+func balance(t *testing.T, h http.Handler, accID int64) int64 {
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d", accID)
+	w := serve(h, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Balance int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].Balance
+}
+
+func createTxID(t *testing.T, h http.Handler) int64 {
+	w := serve(h, "POST", "/v1/mainnet/transactions/", struct {
+		N int64 `json:"n"`
+	}{
+		N: 1,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].ID
+}
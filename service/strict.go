@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// maxStrictBodySize is the largest request body accepted in strict mode.
+const maxStrictBodySize = 1 << 20
+
+var errBodyTooLarge = newError("body_too_large", "request body too large")
+
+// decodeJSON decodes the JSON value in r into v. In strict mode fields that v
+// does not have and data following the value are rejected.
+func (c *chain) decodeJSON(r io.Reader, v interface{}) error {
+	return c.decode(json.NewDecoder(r), v)
+}
+
+// decode is decodeJSON for a decoder set up by the caller, for example to keep
+// numbers as they are written.
+func (c *chain) decode(dec *json.Decoder, v interface{}) error {
+	if c.strict {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		if !c.strict {
+			return errInvalidJSON
+		}
+		return errorf("invalid_json", "invalid json: %v", err)
+	}
+
+	if c.strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return newError("invalid_json", "invalid json: trailing data")
+		}
+	}
+	return nil
+}
+
+// strictMiddleware, in strict mode, requires every request to accept JSON
+// and those with a body to send JSON of at most 1MB.
+func (c *chain) strictMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !c.strict {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !acceptHeaderFound(w, r) {
+				return
+			}
+
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body,
+				maxStrictBodySize+1))
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			if len(body) > maxStrictBodySize {
+				sendError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if len(body) > 0 && !contentTypeHeaderFound(w, r) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Strict is an option that can be passed to New() to make the specified
+// network reject requests that the production service may not accept. Every
+// request must have an "Accept: application/json" header and those with a
+// body a "Content-Type: application/json" header. Bodies larger than 1MB,
+// JSON with unknown fields, values of the wrong type or trailing data are
+// rejected.
+func Strict(network Network) Option {
+	return func(c *chain) {
+		if c.network == network {
+			c.strict = true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestStrict(t *testing.T) {
+	s := service.New(service.Strict(service.MainNet))
+
+	fromAccID := fundedAccount(t, s, 1000)
+	toAccID := createAccount(t, s)
+
+	send := func(body, accept, contentType string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/v1/mainnet/transactions/",
+			strings.NewReader(body))
+		r.SetBasicAuth("user", "pass")
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	transfer := func(extra string) string {
+		return fmt.Sprintf(
+			`{"id":%d,"fromAccountID":%d,"toAccountID":%d,"value":10%s}`,
+			createTxID(t, s), fromAccID, toAccID, extra)
+	}
+
+	w := send(transfer(`,"memo":"x"`), "application/json", "application/json")
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_json")
+
+	// txIndex is assigned by the debit and not accepted as input.
+	w = send(fmt.Sprintf(`{"id":%d,"fromAccountID":%d,"outputs":[`+
+		`{"toAddress":"1BoatSLRHtKNngkdXEeobR76b53LETtpyT","value":10,`+
+		`"txIndex":3}]}`, createTxID(t, s), fromAccID), "application/json",
+		"application/json")
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_json")
+
+	w = send(transfer("")+"{}", "application/json", "application/json")
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_json")
+
+	w = send(`{"id":"1"}`, "application/json", "application/json")
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_json")
+
+	w = send(transfer(""), "", "application/json")
+	expectErrorCode(t, w, http.StatusBadRequest, "header_not_found")
+
+	w = send(transfer(""), "application/json", "")
+	expectErrorCode(t, w, http.StatusBadRequest, "header_not_found")
+
+	w = send(strings.Repeat(" ", 1<<20+1), "application/json",
+		"application/json")
+	expectErrorCode(t, w, http.StatusRequestEntityTooLarge, "body_too_large")
+
+	w = send(transfer(""), "application/json", "application/json")
+	expectCode(t, w, http.StatusCreated)
+
+	// Stubs are decoded strictly as well.
+	r := httptest.NewRequest("POST", "/_mock/mainnet/stubs/",
+		strings.NewReader(`{"method":"GET","path":"/fees/","resp":{}}`))
+	r.SetBasicAuth("admin", "pass")
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectErrorCode(t, w, http.StatusBadRequest, "invalid_json")
+
+	// Other networks are lenient.
+	r = httptest.NewRequest("GET", "/v1/testnet3/transactions/1", nil)
+	r.SetBasicAuth("user", "pass")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectErrorCode(t, w, http.StatusNotFound, "transaction_not_found")
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Stub is a canned response returned, instead of calling the endpoint, for
+// requests to a network that match its Method, Path, Header and Body. Stubs
+// take precedence over every endpoint and do not change the state of the
+// service.
+type Stub struct {
+	// ID is assigned when the stub is added.
+	ID int64 `json:"id"`
+
+	// Method is the HTTP method to match. Empty matches every method.
+	Method string `json:"method"`
+
+	// Path is a pattern, as used by path.Match, matched against the request
+	// path after /v1/[network]. Empty matches every path.
+	Path string `json:"path"`
+
+	// Header holds headers the request must have with the given values.
+	Header map[string]string `json:"header,omitempty"`
+
+	// Body holds top level fields the JSON request body must contain with the
+	// given values.
+	Body map[string]interface{} `json:"body,omitempty"`
+
+	// Times is the number of requests the stub responds to. Zero means no
+	// limit.
+	Times int `json:"times"`
+
+	Response StubResponse `json:"response"`
+
+	// Hits is the number of requests the stub has responded to.
+	Hits int `json:"hits"`
+}
+
+// StubResponse is the response sent by a Stub.
+type StubResponse struct {
+	// Status defaults to 200.
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+
+	// Body is sent with a "Content-Type: application/json" header unless
+	// Header sets another.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+func (s Stub) validate() error {
+	if s.Times < 0 {
+		return errors.New("times must be >= 0")
+	}
+	if s.Response.Status != 0 &&
+		(s.Response.Status < 100 || s.Response.Status > 599) {
+		return errors.New("invalid status")
+	}
+	if _, err := path.Match(s.Path, ""); err != nil {
+		return errors.New("invalid path")
+	}
+	return nil
+}
+
+func (s Stub) matches(prefix string, r *http.Request, body string) bool {
+	if s.Times > 0 && s.Hits >= s.Times {
+		return false
+	}
+	if !requestMatches(s.Method, s.Path, prefix, r.Method, r.URL.Path) {
+		return false
+	}
+	for key, value := range s.Header {
+		if r.Header.Get(key) != value {
+			return false
+		}
+	}
+	return bodyMatches(s.Body, body)
+}
+
+func (c *chain) AddStub(s Stub) (Stub, error) {
+	if err := s.validate(); err != nil {
+		return Stub{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s.ID = c.nextID()
+	s.Hits = 0
+	c.stubs = append(c.stubs, &s)
+	return s, nil
+}
+
+func (c *chain) Stubs() []Stub {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stubs := make([]Stub, len(c.stubs))
+	for i, s := range c.stubs {
+		stubs[i] = *s
+	}
+	return stubs
+}
+
+func (c *chain) RemoveStub(id int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, s := range c.stubs {
+		if s.ID == id {
+			c.stubs = append(c.stubs[:i], c.stubs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *chain) ClearStubs() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stubs = nil
+}
+
+// stub returns the response of the first stub, in the order they were added,
+// that matches r and records the hit.
+func (c *chain) stub(prefix string, r *http.Request,
+	body string) (StubResponse, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range c.stubs {
+		if s.matches(prefix, r, body) {
+			s.Hits++
+			return s.Response, true
+		}
+	}
+	return StubResponse{}, false
+}
+
+// stubMiddleware responds to requests to endpoints under prefix that match a
+// stub.
+func (c *chain) stubMiddleware(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			resp, exists := c.stub(prefix, r, string(body))
+			if !exists {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			for key, value := range resp.Header {
+				w.Header().Set(key, value)
+			}
+			status := resp.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			w.WriteHeader(status)
+			w.Write(resp.Body)
+		})
+	}
+}
+
+// AddStub adds a stub to network and returns it with its ID set.
+func (s *Service) AddStub(network Network, stub Stub) (Stub, error) {
+	return s.chain(network).AddStub(stub)
+}
+
+// Stubs returns the stubs of network with their hit counts.
+func (s *Service) Stubs(network Network) []Stub {
+	return s.chain(network).Stubs()
+}
+
+// RemoveStub removes the stub id from network.
+func (s *Service) RemoveStub(network Network, id int64) bool {
+	return s.chain(network).RemoveStub(id)
+}
+
+// ClearStubs removes every stub from network.
+func (s *Service) ClearStubs(network Network) {
+	s.chain(network).ClearStubs()
+}
+
+func (c *chain) getStubsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "stubs", "", c.Stubs())
+}
+
+func (c *chain) postStubsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	if !contentTypeHeaderFound(w, r) {
+		return
+	}
+
+	// Numbers are kept as they are written so that large IDs in the Body
+	// fields match exactly.
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	s := Stub{}
+	if err := c.decode(dec, &s); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s, err := c.AddStub(s)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sendPayload(w, http.StatusCreated, "stubs", "", []Stub{s})
+}
+
+func (c *chain) deleteStubsHandler(w http.ResponseWriter, r *http.Request) {
+	c.ClearStubs()
+}
+
+func (c *chain) deleteStubHandler(w http.ResponseWriter, r *http.Request) {
+
+	idValue := mux.Vars(r)["stub-id"]
+	id, err := strconv.ParseInt(idValue, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !c.RemoveStub(id) {
+		sendError(w, http.StatusNotFound,
+			newError("stub_not_found", "stub not found"))
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestStub(t *testing.T) {
+	s := service.New()
+
+	stub, err := s.AddStub(service.MainNet, service.Stub{
+		Method: "GET",
+		Path:   "/fees/",
+		Times:  1,
+		Response: service.StubResponse{
+			Status: http.StatusServiceUnavailable,
+			Body:   json.RawMessage(`{"type":"errors","payload":[]}`),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusServiceUnavailable)
+	if body := w.Body.String(); body != `{"type":"errors","payload":[]}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	// The stub is exhausted so the endpoint responds.
+	w = serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	stubs := s.Stubs(service.MainNet)
+	if len(stubs) != 1 || stubs[0].ID != stub.ID || stubs[0].Hits != 1 {
+		t.Fatalf("unexpected stubs %+v", stubs)
+	}
+}
+
+func TestStubEndpoint(t *testing.T) {
+	s := service.New()
+
+	accID := createAccount(t, s)
+
+	w := serveAdmin(s, "POST", "/_mock/mainnet/stubs/", service.Stub{
+		Method: "PUT",
+		Path:   "/transactions/",
+		Body: map[string]interface{}{
+			"fromAccountID": accID,
+		},
+		Response: service.StubResponse{
+			Status: http.StatusCreated,
+		},
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []service.Stub
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	// The account has no funds but the stub responds with success.
+	type transfer struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}
+	tx := transfer{
+		ID:            createTxID(t, s),
+		FromAccountID: accID,
+		ToAccountID:   createAccount(t, s),
+		Value:         10,
+	}
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", tx)
+	expectCode(t, w, http.StatusCreated)
+
+	url := fmt.Sprintf("/_mock/mainnet/stubs/%d", res.Payload[0].ID)
+	w = serveAdmin(s, "DELETE", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", tx)
+	expectCode(t, w, http.StatusBadRequest)
+}
+
+func TestStubAuth(t *testing.T) {
+	s := service.New(service.Credentials(service.MainNet, service.Credential{
+		User: "reports", Pass: "secret", Role: service.ReadOnly,
+	}))
+
+	if _, err := s.AddStub(service.MainNet, service.Stub{
+		Method:   "PUT",
+		Path:     "/transactions/",
+		Response: service.StubResponse{Status: http.StatusCreated},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stubs only answer requests that the service would accept.
+	w := serveAs(s, "user", "wrong", "PUT", "/v1/mainnet/transactions/",
+		nil)
+	expectCode(t, w, http.StatusUnauthorized)
+
+	w = serveAs(s, "reports", "secret", "PUT", "/v1/mainnet/transactions/",
+		nil)
+	expectCode(t, w, http.StatusForbidden)
+
+	if stubs := s.Stubs(service.MainNet); stubs[0].Hits != 0 {
+		t.Fatalf("unexpected stubs %+v", stubs)
+	}
+
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", nil)
+	expectCode(t, w, http.StatusCreated)
+}
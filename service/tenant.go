@@ -0,0 +1,224 @@
+package service
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// tenantHeader selects the tenant of a request to a network with the Tenants
+// option set.
+const tenantHeader = "X-Mock-Tenant"
+
+// tenant is a chain and its endpoints.
+type tenant struct {
+	chain        *chain
+	handler      http.Handler
+	adminHandler http.Handler
+}
+
+func newTenant(c *chain) *tenant {
+	name := c.params().Name
+	return &tenant{
+		chain:        c,
+		handler:      c.handler("/v1/" + name),
+		adminHandler: c.adminHandler("/_mock/" + name),
+	}
+}
+
+// tenants holds the default tenant of a network and, if the Tenants option is
+// set, those created on first use.
+type tenants struct {
+	mu sync.Mutex
+
+	options []Option
+	base    *tenant
+	named   map[string]*tenant
+}
+
+func newTenants(c *chain, options []Option) *tenants {
+	return &tenants{
+		options: options,
+		base:    newTenant(c),
+		named:   make(map[string]*tenant),
+	}
+}
+
+// lookup returns the tenant name if it exists.
+func (ts *tenants) lookup(name string) (*tenant, bool) {
+	if !ts.base.chain.multiTenant || name == "" ||
+		name == ts.base.chain.user {
+		return ts.base, true
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t, exists := ts.named[name]
+	return t, exists
+}
+
+// get returns the tenant name, creating it if it does not exist.
+func (ts *tenants) get(name string) *tenant {
+	if t, exists := ts.lookup(name); exists {
+		return t
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t, exists := ts.named[name]
+	if !exists {
+		t = newTenant(newChain(ts.base.chain.network, ts.options))
+		ts.named[name] = t
+	}
+	return t
+}
+
+// serve serves r with the handler h of the tenant name. A tenant that does
+// not exist is only created once r is authenticated, by the credentials of
+// the default tenant, with at least the required role, so unauthenticated
+// requests can not create tenants.
+func (ts *tenants) serve(w http.ResponseWriter, r *http.Request, name string,
+	required Role, h func(t *tenant) http.Handler) {
+
+	if t, exists := ts.lookup(name); exists {
+		h(t).ServeHTTP(w, r)
+		return
+	}
+
+	create := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h(ts.get(name)).ServeHTTP(w, r)
+	})
+	ts.base.chain.authMiddleware(required)(create).ServeHTTP(w, r)
+}
+
+func (ts *tenants) Names() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	names := make([]string, 0, len(ts.named))
+	for name := range ts.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (ts *tenants) Delete(name string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, exists := ts.named[name]; !exists {
+		return false
+	}
+	delete(ts.named, name)
+	return true
+}
+
+// tenantName returns the name of the tenant serving r: the one given by the
+// X-Mock-Tenant header or, if it is not set, the one whose credentials
+// authenticate r. Credentials given to New are those of every tenant, so
+// select the default tenant. Otherwise the basic authentication user name
+// is used.
+func (ts *tenants) tenantName(r *http.Request) string {
+	if name := r.Header.Get(tenantHeader); name != "" {
+		return name
+	}
+	if _, exists := ts.base.chain.namedCredential(r); exists {
+		return ""
+	}
+
+	for _, name := range ts.Names() {
+		t, exists := ts.lookup(name)
+		if !exists {
+			continue
+		}
+		if _, exists := t.chain.namedCredential(r); exists {
+			return name
+		}
+	}
+
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// handler returns the endpoints of the network, served by the tenant given by
+// tenantName.
+func (ts *tenants) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ts.tenantName(r)
+		ts.serve(w, r, name, Full, func(t *tenant) http.Handler {
+			return t.handler
+		})
+	})
+}
+
+// routes returns the /_mock/ endpoints of the network that are not served by
+// a tenant.
+func (ts *tenants) routes() []route {
+	return []route{
+		{method: "GET", path: "/tenants/",
+			handler: ts.getTenantsHandler,
+			summary: "List the tenants that have been used",
+			status:  http.StatusOK, ty: "tenants",
+			response: ""},
+		{method: "DELETE", path: "/tenants/{tenant}",
+			handler: ts.deleteTenantHandler,
+			summary: "Delete a tenant",
+			status:  http.StatusOK},
+		{method: "GET", path: "/openapi.json",
+			handler: ts.getOpenAPIHandler,
+			summary: "Get the OpenAPI document describing the network",
+			status:  http.StatusOK, response: map[string]interface{}{}},
+	}
+}
+
+// adminHandler returns the /_mock/ endpoints of the network. Requests are
+// served by the tenant given by the X-Mock-Tenant header or, if it is not
+// set, the default tenant.
+func (ts *tenants) adminHandler() http.Handler {
+	c := ts.base.chain
+	prefix := "/_mock/" + c.params().Name
+
+	root := mux.NewRouter()
+	root.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	router := root.PathPrefix(prefix).Subrouter()
+
+	mw := middleware{
+		c.authMiddleware(Admin),
+	}
+
+	handle(router, mw, ts.routes())
+
+	root.NotFoundHandler = notFoundHandler(root,
+		func(w http.ResponseWriter, r *http.Request) {
+			name := r.Header.Get(tenantHeader)
+			ts.serve(w, r, name, Admin, func(t *tenant) http.Handler {
+				return t.adminHandler
+			})
+		})
+	return root
+}
+
+func (ts *tenants) getTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	if !acceptHeaderFound(w, r) {
+		return
+	}
+
+	sendPayload(w, http.StatusOK, "tenants", "", ts.Names())
+}
+
+// deleteTenantHandler removes a tenant. It is created again, in its initial
+// state, when next used.
+func (ts *tenants) deleteTenantHandler(w http.ResponseWriter,
+	r *http.Request) {
+
+	if !ts.Delete(mux.Vars(r)["tenant"]) {
+		sendError(w, http.StatusNotFound,
+			newError("tenant_not_found", "tenant not found"))
+		return
+	}
+}
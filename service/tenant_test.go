@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func TestTenants(t *testing.T) {
+	s := service.New(service.Tenants(service.MainNet))
+
+	w := serveAs(s, "suite-a", "pass", "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d", res.Payload[0].ID)
+
+	w = serveAs(s, "suite-a", "pass", "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	// Other tenants, including the default one, have their own accounts.
+	w = serveAs(s, "suite-b", "pass", "GET", url, nil)
+	expectCode(t, w, http.StatusNotFound)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusNotFound)
+
+	// The header takes precedence over the user name.
+	r := httptest.NewRequest("GET", url, nil)
+	r.SetBasicAuth("user", "pass")
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("X-Mock-Tenant", "suite-a")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusOK)
+
+	w = serveAs(s, "suite-a", "wrong", "GET", url, nil)
+	expectCode(t, w, http.StatusUnauthorized)
+
+	// Unauthenticated requests do not create tenants.
+	w = serveAs(s, "attacker", "wrong", "GET", url, nil)
+	expectCode(t, w, http.StatusUnauthorized)
+	r = httptest.NewRequest("POST", "/_mock/mainnet/reset/", nil)
+	r.SetBasicAuth("suite-c", "pass")
+	r.Header.Set("X-Mock-Tenant", "suite-c")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusForbidden)
+
+	// Admin requests select the tenant with the header.
+	r = httptest.NewRequest("POST", "/_mock/mainnet/reset/", nil)
+	r.SetBasicAuth("admin", "pass")
+	r.Header.Set("X-Mock-Tenant", "suite-a")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusOK)
+
+	w = serveAs(s, "suite-a", "pass", "GET", url, nil)
+	expectCode(t, w, http.StatusNotFound)
+
+	w = serveAdmin(s, "GET", "/_mock/mainnet/tenants/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	tenants := struct {
+		Payload []string
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&tenants); err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants.Payload) != 2 {
+		t.Fatalf("expected 2 tenants got %v", tenants.Payload)
+	}
+
+	w = serveAdmin(s, "DELETE", "/_mock/mainnet/tenants/suite-b", nil)
+	expectCode(t, w, http.StatusOK)
+	w = serveAdmin(s, "DELETE", "/_mock/mainnet/tenants/suite-b", nil)
+	expectCode(t, w, http.StatusNotFound)
+}
+
+func TestTenantsDisabled(t *testing.T) {
+	s := service.New(service.Tenants(service.TestNet3))
+
+	w := serveAs(s, "suite-a", "pass", "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusUnauthorized)
+
+	w = serveAs(s, "suite-a", "pass", "GET", "/v1/testnet3/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+}
+
+func TestTenantsCredentials(t *testing.T) {
+	s := service.New(service.Tenants(service.MainNet),
+		service.Credentials(service.MainNet, service.Credential{
+			User: "reports", Pass: "secret", Role: service.ReadOnly,
+		}))
+
+	// Credentials given to New use the default tenant.
+	w := serveAs(s, "reports", "secret", "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+
+	accID := createAccountAs(t, s, "suite-a")
+
+	// Credentials added to a tenant use that tenant.
+	r := httptest.NewRequest("POST", "/_mock/mainnet/credentials/",
+		strings.NewReader(fmt.Sprintf(
+			`{"user":"scoped","pass":"secret","accountIDs":[%d]}`, accID)))
+	r.SetBasicAuth("admin", "pass")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Mock-Tenant", "suite-a")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	expectCode(t, w, http.StatusCreated)
+
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d", accID)
+	w = serveAs(s, "scoped", "secret", "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+	w = serveAs(s, "scoped", "secret", "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusForbidden)
+
+	w = serveAdmin(s, "GET", "/_mock/mainnet/tenants/", nil)
+	expectCode(t, w, http.StatusOK)
+	tenants := struct {
+		Payload []string
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&tenants); err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants.Payload) != 1 || tenants.Payload[0] != "suite-a" {
+		t.Fatalf("expected tenant suite-a got %v", tenants.Payload)
+	}
+}
+
+// createAccountAs creates an account in the tenant of user.
+func createAccountAs(t *testing.T, h http.Handler, user string) int64 {
+	w := serveAs(h, user, "pass", "POST", "/v1/mainnet/accounts/", nil)
+	expectCode(t, w, http.StatusCreated)
+
+	res := struct {
+		Payload []struct {
+			ID int64
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Payload[0].ID
+}
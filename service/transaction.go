@@ -1,8 +1,8 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -22,23 +22,20 @@ func (c *chain) postTransactionsHandler(w http.ResponseWriter,
 		return
 	}
 
-	n := struct {
-		N int `json:"n"`
-	}{}
+	n := countPayload{}
 
-	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
-		sendError(w, http.StatusBadRequest, "invalid json")
+	if err := c.decodeJSON(r.Body, &n); err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	if n.N < 1 || n.N > 10 {
-		sendError(w, http.StatusBadRequest, "n must be > 0 and <= 10")
+		sendError(w, http.StatusBadRequest,
+			newError("invalid_n", "n must be > 0 and <= 10"))
 		return
 	}
 
-	idsPayload := make([]struct {
-		ID int64 `json:"id"`
-	}, n.N)
+	idsPayload := make([]transactionIDPayload, n.N)
 
 	for i := range idsPayload {
 		idsPayload[i].ID = c.CreateTransactionID()
@@ -47,78 +44,211 @@ func (c *chain) postTransactionsHandler(w http.ResponseWriter,
 	sendPayload(w, http.StatusCreated, "transactions", "", idsPayload)
 }
 
+type transactionIDPayload struct {
+	ID int64 `json:"id"`
+}
+
+const (
+	maxBatchTransactions = 50
+	maxDebitOutputs      = 50
+)
+
+type putTransactionPayload struct {
+	ID            int64  `json:"id"`
+	FromAccountID int64  `json:"fromAccountID"`
+	ToAccountID   int64  `json:"toAccountID"`
+	ToAddress     string `json:"toAddress"`
+	Value         int64  `json:"value"`
+
+	// Outputs pays several addresses in a single debit instead of
+	// ToAddress.
+	Outputs []putOutputPayload `json:"outputs,omitempty"`
+}
+
+// putOutputPayload is an address paid by a debit. Unlike Output it has no
+// txIndex, which is assigned when the debit is made.
+type putOutputPayload struct {
+	ToAddress string `json:"toAddress"`
+	Value     int64  `json:"value"`
+}
+
+// Output is a payment to an address made by a debit or received by a credit.
+type Output struct {
+	ToAddress string `json:"toAddress"`
+	Value     int64  `json:"value"`
+	TxIndex   int64  `json:"txIndex"`
+}
+
+// putTransactionsHandler applies a single transaction or, if the body is a
+// JSON array, a batch of transactions that either all succeed or all fail.
 func (c *chain) putTransactionsHandler(w http.ResponseWriter,
 	r *http.Request) {
 
-	pl := struct {
-		ID            int64
-		FromAccountID int64
-		ToAccountID   int64
-		ToAddress     string
-		Value         int64
-	}{}
+	var body json.RawMessage
+	if err := c.decodeJSON(r.Body, &body); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	batch := len(body) > 0 && body[0] == '['
 
-	if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
-		sendError(w, http.StatusBadRequest, "invalid json")
+	pls := []putTransactionPayload{}
+	if batch {
+		if err := c.decodeJSON(bytes.NewReader(body), &pls); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(pls) < 1 || len(pls) > maxBatchTransactions {
+			sendError(w, http.StatusBadRequest, errorf("invalid_batch",
+				"number of transactions must be > 0 and <= %d",
+				maxBatchTransactions))
+			return
+		}
+	} else {
+		pl := putTransactionPayload{}
+		if err := c.decodeJSON(bytes.NewReader(body), &pl); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		pls = append(pls, pl)
+	}
+
+	code := http.StatusBadRequest
+	errs := make([]error, len(pls))
+	txns := make([]transaction, len(pls))
+	failed := false
+	for i, pl := range pls {
+		txns[i], errs[i] = c.transactionFromPayload(pl)
+		if errs[i] == nil && !allowsAccount(r, pl.FromAccountID) {
+			errs[i] = errAccountForbidden
+		}
+		failed = failed || errs[i] != nil
+	}
+	if !failed {
+		errs = c.Transactions(txns)
+	}
+
+	if errs != nil {
+		pl := []errorPayload{}
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			if err == errTxIDConflict {
+				code = http.StatusConflict
+			} else if err == errAccountForbidden {
+				code = http.StatusForbidden
+			}
+			e := newErrorPayload(http.StatusBadRequest, err)
+			if batch {
+				index := i
+				e.Index = &index
+			}
+			pl = append(pl, e)
+		}
+		sendErrors(w, code, pl)
 		return
 	}
 
+	w.WriteHeader(http.StatusCreated)
+}
+
+var (
+	errInvalidFromAccountID = newError("invalid_account_id",
+		"invalid fromAccountID")
+	errInvalidToAccountID = newError("invalid_account_id",
+		"invalid toAccountID")
+
+	errInvalidToAddress = newError("invalid_address", "invalid toAddress")
+	errNotPubKeyHash    = newError("invalid_address",
+		"toAddress not public key hash")
+	errWrongChain = newError("invalid_address", "toAddress for wrong chain")
+)
+
+// transactionFromPayload validates pl and returns the transfer or debit it
+// describes.
+func (c *chain) transactionFromPayload(
+	pl putTransactionPayload) (transaction, error) {
+
 	if pl.ID <= 0 {
-		sendError(w, http.StatusBadRequest, "invalid id")
-		return
+		return transaction{}, errInvalidTxID
 	}
 
 	if pl.FromAccountID <= 0 {
-		sendError(w, http.StatusBadRequest, "invalid fromAccountID")
-		return
+		return transaction{}, errInvalidFromAccountID
+	}
+
+	if len(pl.Outputs) > 0 {
+		return c.debitFromOutputs(pl.ID, pl.FromAccountID, pl.Outputs)
 	}
 
 	if pl.Value <= 0 {
-		sendError(w, http.StatusBadRequest, "invalid value")
-		return
+		return transaction{}, errInvalidValue
 	}
 
 	if pl.ToAddress == "" {
 		if pl.ToAccountID <= 0 {
-			sendError(w, http.StatusBadRequest, "invalid toAccountID")
-			return
+			return transaction{}, errInvalidToAccountID
 		}
-		err := c.Transfer(pl.ID, pl.FromAccountID, pl.ToAccountID, pl.Value)
-		if err != nil {
-			sendError(w, http.StatusBadRequest, err.Error())
-			return
+		return transaction{
+			id:            pl.ID,
+			ty:            "transfer",
+			fromAccountID: pl.FromAccountID,
+			toAccountID:   pl.ToAccountID,
+			value:         pl.Value,
+		}, nil
+	}
+
+	return c.debitFromOutputs(pl.ID, pl.FromAccountID, []putOutputPayload{
+		{ToAddress: pl.ToAddress, Value: pl.Value},
+	})
+}
+
+// debitFromOutputs validates outputs and returns a debit paying them in a
+// single on-chain transaction.
+func (c *chain) debitFromOutputs(id, fromAccID int64,
+	outputs []putOutputPayload) (transaction, error) {
+
+	if len(outputs) > maxDebitOutputs {
+		return transaction{}, errorf("too_many_outputs", "outputs > %d",
+			maxDebitOutputs)
+	}
+
+	tx := transaction{
+		id:            id,
+		ty:            "debit",
+		fromAccountID: fromAccID,
+	}
+	for _, out := range outputs {
+		if out.Value <= 0 {
+			return transaction{}, errInvalidValue
 		}
-	} else {
 
-		toAddr, err := btcutil.DecodeAddress(pl.ToAddress, c.params())
+		toAddr, err := btcutil.DecodeAddress(out.ToAddress, c.params())
 		if err != nil {
-			sendError(w, http.StatusBadRequest, "invalid toAddress")
-			return
+			return transaction{}, errInvalidToAddress
 		}
 		if _, ok := toAddr.(*btcutil.AddressPubKeyHash); !ok {
-			sendError(w, http.StatusBadRequest,
-				"toAddress not public key hash")
-			return
+			return transaction{}, errNotPubKeyHash
 		}
 		if !toAddr.IsForNet(c.params()) {
-			sendError(w, http.StatusBadRequest,
-				"toAddress for wrong chain")
-			return
+			return transaction{}, errWrongChain
 		}
 
-		if err := c.Debit(pl.ID,
-			pl.FromAccountID, pl.ToAddress, pl.Value); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+		tx.outputs = append(tx.outputs, output{
+			address: out.ToAddress,
+			value:   out.Value,
+		})
+		tx.value += out.Value
 	}
-
-	w.WriteHeader(http.StatusCreated)
+	return tx, nil
 }
 
-type transactionPayload struct {
-	ID   int64  `json:"id"`
-	Type string `json:"type"`
+// Transaction is a transaction as returned by the /transactions/ endpoints.
+type Transaction struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"`
 
 	FromAccountID int64 `json:"fromAccountID"`
 	ToAccountID   int64 `json:"toAccountID"`
@@ -135,6 +265,78 @@ type transactionPayload struct {
 
 	TxHashes []string `json:"txHashes,omitempty"`
 	TxIndex  int64    `json:"txIndex,omitempty"`
+
+	Outputs []Output `json:"outputs,omitempty"`
+	Fee     int64    `json:"fee,omitempty"`
+
+	FromAddress   string `json:"fromAddress,omitempty"`
+	Confirmations int64  `json:"confirmations,omitempty"`
+}
+
+func newTransaction(tx transaction) Transaction {
+	pl := Transaction{
+		ID:     tx.id,
+		Type:   tx.ty,
+		Status: tx.status,
+
+		FromAccountID: tx.fromAccountID,
+		ToAccountID:   tx.toAccountID,
+
+		Value:   tx.value,
+		Created: tx.created,
+
+		Fee: tx.fee,
+
+		FromAddress:   tx.fromAddress,
+		Confirmations: tx.confirmations,
+	}
+	if tx.txHash != "" {
+		pl.TxHashes = []string{tx.txHash}
+	}
+	if len(tx.outputs) == 1 {
+		pl.TxIndex = tx.outputs[0].txIndex
+	}
+	for _, out := range tx.outputs {
+		pl.Outputs = append(pl.Outputs, Output{
+			ToAddress: out.address,
+			Value:     out.value,
+			TxIndex:   out.txIndex,
+		})
+	}
+	return pl
+}
+
+// toTransaction returns the transaction described by pl.
+func toTransaction(pl Transaction) transaction {
+	tx := transaction{
+		id:     pl.ID,
+		ty:     pl.Type,
+		status: pl.Status,
+
+		fromAccountID: pl.FromAccountID,
+		toAccountID:   pl.ToAccountID,
+
+		value:   pl.Value,
+		created: pl.Created,
+
+		fee: pl.Fee,
+
+		fromAddress: pl.FromAddress,
+	}
+	if len(pl.TxHashes) > 0 {
+		tx.txHash = pl.TxHashes[0]
+	}
+	for _, out := range pl.Outputs {
+		tx.outputs = append(tx.outputs, output{
+			address: out.ToAddress,
+			value:   out.Value,
+			txIndex: out.TxIndex,
+		})
+	}
+	if pl.Type == "credit" && len(tx.outputs) == 0 && pl.TxIndex != 0 {
+		tx.outputs = []output{{value: pl.Value, txIndex: pl.TxIndex}}
+	}
+	return tx
 }
 
 func (c *chain) getTransactionHandler(w http.ResponseWriter,
@@ -143,29 +345,39 @@ func (c *chain) getTransactionHandler(w http.ResponseWriter,
 	txIDValue := mux.Vars(r)["transaction-id"]
 	txID, err := strconv.ParseInt(txIDValue, 10, 64)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	// Scoped credentials can not see transactions of other accounts.
 	tx, exists := c.Transaction(txID)
-	if !exists {
-		http.Error(w, "transaction not found", http.StatusNotFound)
+	if !exists || (tx.ty != "" && !allowsAccount(r, tx.fromAccountID) &&
+		!allowsAccount(r, tx.toAccountID)) {
+		sendError(w, http.StatusNotFound, errTxNotFound)
 		return
 	}
 
 	sendPayload(w, http.StatusOK, "transactions", "",
-		[]transactionPayload{
-			{
-				ID:   txID,
-				Type: tx.ty,
+		[]Transaction{newTransaction(tx)})
+}
 
-				FromAccountID: tx.fromAccountID,
-				ToAccountID:   tx.toAccountID,
+func (c *chain) deleteTransactionHandler(w http.ResponseWriter,
+	r *http.Request) {
 
-				Value:   tx.value,
-				Created: tx.created,
-			},
-		})
+	txIDValue := mux.Vars(r)["transaction-id"]
+	txID, err := strconv.ParseInt(txIDValue, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.CancelTransactionID(txID); err == errTxNotFound {
+		sendError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
 }
 
 func (c *chain) getAccountTransactions(w http.ResponseWriter,
@@ -177,53 +389,62 @@ func (c *chain) getAccountTransactions(w http.ResponseWriter,
 
 	accIDValue := mux.Vars(r)["account-id"]
 	accID, err := strconv.ParseInt(accIDValue, 10, 64)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err != nil || accID < 1 {
+		sendError(w, http.StatusBadRequest, errInvalidAccountID)
 		return
 	}
 
-	if accID < 1 {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !allowsAccount(r, accID) {
+		sendError(w, http.StatusForbidden, errAccountForbidden)
 		return
 	}
 
-	limitValue := r.URL.Query().Get("limit")
-	limit, err := strconv.Atoi(limitValue)
-	if limitValue == "" {
-		limit = getAccountsLimit
-	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	} else if limit > getAccountsLimitMax {
-		errStr := fmt.Sprintf("limit > %d", getAccountsLimitMax)
-		http.Error(w, errStr, http.StatusBadRequest)
+	limit, next, err := pageParams(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	nextValue := r.URL.Query().Get("next")
-	next, err := strconv.Atoi(nextValue)
-	if nextValue == "" {
-		next = 0
-	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	payload := []Transaction{}
+	for _, tx := range c.AccountTransactions(accID, limit, next) {
+		payload = append(payload, newTransaction(tx))
 	}
+	sendPayload(w, http.StatusOK, "transactions",
+		nextPage(limit, next, len(payload)), payload)
+}
 
-	payload := []transactionPayload{}
-	for i, tx := range c.AccountTransactions(accID, limit, next) {
-		if i < next {
-			continue
-		}
-		payload = append(payload, transactionPayload{
-			ID:   tx.id,
-			Type: tx.ty,
+// Transaction returns the transaction id of network.
+func (s *Service) Transaction(network Network, id int64) (Transaction, bool) {
+	tx, exists := s.chain(network).Transaction(id)
+	return newTransaction(tx), exists
+}
 
-			FromAccountID: tx.fromAccountID,
-			ToAccountID:   tx.toAccountID,
+// Transfer moves value between two accounts of network as if requested with
+// PUT /transactions/ and returns the transfer.
+func (s *Service) Transfer(network Network,
+	fromAccID, toAccID, value int64) (Transaction, error) {
 
-			Value:   tx.value,
-			Created: tx.created,
-		})
+	c := s.chain(network)
+	txID := c.CreateTransactionID()
+	if err := c.Transfer(txID, fromAccID, toAccID, value); err != nil {
+		return Transaction{}, err
+	}
+	tx, _ := c.Transaction(txID)
+	return newTransaction(tx), nil
+}
+
+// InjectTransaction records the transfer, debit or credit tx on network and
+// applies it to the balances of its accounts. Funds are not checked and no
+// fees are charged so that the history of accounts can be recreated. tx is
+// given an ID if tx.ID is 0 and the current time if tx.Created is zero. A
+// credit pays tx.ToAccountID from the output tx.TxIndex of tx.TxHashes[0],
+// which must not have been credited already, and a debit must have outputs.
+func (s *Service) InjectTransaction(network Network,
+	tx Transaction) (Transaction, error) {
+
+	injected, err := s.chain(network).InjectTransaction(toTransaction(tx))
+	if err != nil {
+		return Transaction{}, err
 	}
-	sendPayload(w, http.StatusOK, "transactions", "", payload)
+	return newTransaction(injected), nil
 }
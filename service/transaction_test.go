@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/rtwire/mock/service"
 )
@@ -184,6 +185,7 @@ func TestTransations(t *testing.T) {
 
 	txns := &struct {
 		Status  string
+		Next    string
 		Payload []struct {
 			ID   int64
 			Type string
@@ -197,4 +199,404 @@ func TestTransations(t *testing.T) {
 	if len(txns.Payload) != 2 {
 		t.Fatal("incorrect number of transactions")
 	}
+
+	// A full page gives the next value of the following page.
+	r = httptest.NewRequest("GET", url+"?limit=1", nil)
+	r.SetBasicAuth("user", "pass")
+	r.Header.Add("Accept", "application/json")
+	w = httptest.NewRecorder()
+
+	s.ServeHTTP(w, r)
+
+	txns.Payload = nil
+	if err := json.NewDecoder(w.Body).Decode(txns); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(txns.Payload) != 1 || txns.Next != "1" {
+		t.Fatalf("unexpected page %+v", txns)
+	}
+
+	// A limit past the last transaction returns the rest of them.
+	r = httptest.NewRequest("GET", url+"?limit=3", nil)
+	r.SetBasicAuth("user", "pass")
+	r.Header.Add("Accept", "application/json")
+	w = httptest.NewRecorder()
+
+	s.ServeHTTP(w, r)
+
+	txns.Payload = nil
+	txns.Next = ""
+	if err := json.NewDecoder(w.Body).Decode(txns); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(txns.Payload) != 2 || txns.Next != "" {
+		t.Fatalf("unexpected page %+v", txns)
+	}
+}
+
+func TestTransactionsIdempotent(t *testing.T) {
+	s := service.New()
+
+	fromAccID := fundedAccount(t, s, 10)
+	toAccID := createAccount(t, s)
+	txID := createTxID(t, s)
+
+	type transfer struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}
+	tx := transfer{
+		ID:            txID,
+		FromAccountID: fromAccID,
+		ToAccountID:   toAccID,
+		Value:         4,
+	}
+
+	// Resubmitting an identical transaction returns the original success
+	// without moving funds twice.
+	for i := 0; i < 2; i++ {
+		w := serve(s, "PUT", "/v1/mainnet/transactions/", tx)
+		expectCode(t, w, http.StatusCreated)
+	}
+
+	if b := balance(t, s, fromAccID); b != 6 {
+		t.Fatalf("expected balance 6 got %d", b)
+	}
+	if b := balance(t, s, toAccID); b != 4 {
+		t.Fatalf("expected balance 4 got %d", b)
+	}
+
+	// Reusing the ID with different parameters is a conflict.
+	tx.Value = 5
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", tx)
+	expectCode(t, w, http.StatusConflict)
+
+	// An ID that was never allocated is still invalid.
+	tx.ID = txID + 1
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", tx)
+	expectCode(t, w, http.StatusBadRequest)
+}
+
+func TestTransactionsBatch(t *testing.T) {
+	s := service.New()
+
+	fromAccID := fundedAccount(t, s, 10)
+	toAccIDs := []int64{createAccount(t, s), createAccount(t, s)}
+
+	type transfer struct {
+		ID            int64 `json:"id"`
+		FromAccountID int64 `json:"fromAccountID"`
+		ToAccountID   int64 `json:"toAccountID"`
+		Value         int64 `json:"value"`
+	}
+	batch := []transfer{}
+	for _, toAccID := range toAccIDs {
+		batch = append(batch, transfer{
+			ID:            createTxID(t, s),
+			FromAccountID: fromAccID,
+			ToAccountID:   toAccID,
+			Value:         6,
+		})
+	}
+
+	// The second transfer overdraws the account so neither is applied.
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", batch)
+	expectCode(t, w, http.StatusBadRequest)
+
+	res := struct {
+		Type    string
+		Payload []struct {
+			Index   *int
+			Message string
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Payload) != 1 || res.Payload[0].Index == nil ||
+		*res.Payload[0].Index != 1 {
+		t.Fatalf("unexpected errors %+v", res.Payload)
+	}
+	if b := balance(t, s, fromAccID); b != 10 {
+		t.Fatalf("expected balance 10 got %d", b)
+	}
+
+	batch[1].Value = 4
+	w = serve(s, "PUT", "/v1/mainnet/transactions/", batch)
+	expectCode(t, w, http.StatusCreated)
+
+	for accID, expected := range map[int64]int64{
+		fromAccID:   0,
+		toAccIDs[0]: 6,
+		toAccIDs[1]: 4,
+	} {
+		if b := balance(t, s, accID); b != expected {
+			t.Fatalf("expected balance %d got %d", expected, b)
+		}
+	}
+}
+
+func TestTransactionIDStatus(t *testing.T) {
+	const expiry = time.Hour
+	s := service.New(service.TxIDExpiry(service.MainNet, expiry))
+
+	status := func(txID int64) string {
+		url := fmt.Sprintf("/v1/mainnet/transactions/%d", txID)
+		w := serve(s, "GET", url, nil)
+		expectCode(t, w, http.StatusOK)
+
+		res := struct {
+			Payload []struct {
+				Status string
+			}
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res.Payload[0].Status
+	}
+
+	fromAccID := fundedAccount(t, s, 10)
+	toAccID := createAccount(t, s)
+
+	cancelledTxID := createTxID(t, s)
+	expiredTxID := createTxID(t, s)
+	if st := status(expiredTxID); st != "pending" {
+		t.Fatalf("expected pending got %q", st)
+	}
+
+	url := fmt.Sprintf("/v1/mainnet/transactions/%d", cancelledTxID)
+	w := serve(s, "DELETE", url, nil)
+	expectCode(t, w, http.StatusOK)
+	if st := status(cancelledTxID); st != "cancelled" {
+		t.Fatalf("expected cancelled got %q", st)
+	}
+
+	s.Advance(service.MainNet, 2*expiry)
+	if st := status(expiredTxID); st != "expired" {
+		t.Fatalf("expected expired got %q", st)
+	}
+
+	for _, txID := range []int64{cancelledTxID, expiredTxID} {
+		w := serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+			ID            int64 `json:"id"`
+			FromAccountID int64 `json:"fromAccountID"`
+			ToAccountID   int64 `json:"toAccountID"`
+			Value         int64 `json:"value"`
+		}{
+			ID:            txID,
+			FromAccountID: fromAccID,
+			ToAccountID:   toAccID,
+			Value:         1,
+		})
+		expectCode(t, w, http.StatusBadRequest)
+	}
+
+	if b := balance(t, s, fromAccID); b != 10 {
+		t.Fatalf("expected balance 10 got %d", b)
+	}
+}
+
+func TestTransactionsDebitOutputs(t *testing.T) {
+	s := service.New()
+
+	accID := fundedAccount(t, s, 10000)
+	txID := createTxID(t, s)
+
+	type output struct {
+		ToAddress string `json:"toAddress"`
+		Value     int64  `json:"value"`
+	}
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+		ID            int64    `json:"id"`
+		FromAccountID int64    `json:"fromAccountID"`
+		Outputs       []output `json:"outputs"`
+	}{
+		ID:            txID,
+		FromAccountID: accID,
+		Outputs: []output{
+			{ToAddress: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Value: 1000},
+			{ToAddress: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", Value: 2000},
+		},
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	if b := balance(t, s, accID); b != 7000 {
+		t.Fatalf("expected balance 7000 got %d", b)
+	}
+
+	url := fmt.Sprintf("/v1/mainnet/transactions/%d", txID)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+
+	res := struct {
+		Payload []struct {
+			Value    int64
+			Fee      int64
+			TxHashes []string
+			Outputs  []struct {
+				ToAddress string
+				Value     int64
+				TxIndex   int64
+			}
+		}
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := res.Payload[0]
+	if tx.Value != 3000 || tx.Fee <= 0 || len(tx.TxHashes) != 1 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	if len(tx.Outputs) != 2 {
+		t.Fatalf("expected 2 outputs got %d", len(tx.Outputs))
+	}
+	for i, out := range tx.Outputs {
+		if out.TxIndex != int64(i) {
+			t.Fatalf("expected txIndex %d got %d", i, out.TxIndex)
+		}
+	}
+}
+
+func TestTransactionsInternalDebit(t *testing.T) {
+	s := service.New()
+
+	fromAccID := fundedAccount(t, s, 10000)
+	toAccID := createAccount(t, s)
+	toAddr := createAddress(t, s, toAccID)
+	txID := createTxID(t, s)
+
+	w := serve(s, "PUT", "/v1/mainnet/transactions/", struct {
+		ID            int64  `json:"id"`
+		FromAccountID int64  `json:"fromAccountID"`
+		ToAddress     string `json:"toAddress"`
+		Value         int64  `json:"value"`
+	}{
+		ID:            txID,
+		FromAccountID: fromAccID,
+		ToAddress:     toAddr,
+		Value:         4000,
+	})
+	expectCode(t, w, http.StatusCreated)
+
+	if b := balance(t, s, fromAccID); b != 6000 {
+		t.Fatalf("expected balance 6000 got %d", b)
+	}
+	if b := balance(t, s, toAccID); b != 4000 {
+		t.Fatalf("expected balance 4000 got %d", b)
+	}
+
+	type txns struct {
+		Payload []struct {
+			ID       int64
+			Type     string
+			TxHashes []string
+		}
+	}
+
+	url := fmt.Sprintf("/v1/mainnet/transactions/%d", txID)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+	debit := txns{}
+	if err := json.NewDecoder(w.Body).Decode(&debit); err != nil {
+		t.Fatal(err)
+	}
+
+	url = fmt.Sprintf("/v1/mainnet/accounts/%d/transactions/", toAccID)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+	credits := txns{}
+	if err := json.NewDecoder(w.Body).Decode(&credits); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credits.Payload) != 1 || credits.Payload[0].Type != "credit" {
+		t.Fatalf("unexpected transactions %+v", credits.Payload)
+	}
+	if credits.Payload[0].TxHashes[0] != debit.Payload[0].TxHashes[0] {
+		t.Fatal("credit and debit tx hashes differ")
+	}
+}
+
+func TestInjectTransaction(t *testing.T) {
+	s := service.New()
+
+	from := s.CreateAccount(service.MainNet, 1000)
+	to := s.CreateAccount(service.MainNet, 0)
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tx, err := s.InjectTransaction(service.MainNet, service.Transaction{
+		Type:          "transfer",
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Value:         1500,
+		Created:       created,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.ID == 0 || tx.Status != "complete" || !tx.Created.Equal(created) {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	// Funds are not checked.
+	if b := balance(t, s, from.ID); b != -500 {
+		t.Fatalf("expected balance -500 got %d", b)
+	}
+	if b := balance(t, s, to.ID); b != 1500 {
+		t.Fatalf("expected balance 1500 got %d", b)
+	}
+
+	credit, err := s.InjectTransaction(service.MainNet, service.Transaction{
+		Type:        "credit",
+		ToAccountID: from.ID,
+		Value:       600,
+		FromAddress: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credit.TxHashes) != 1 {
+		t.Fatalf("expected a tx hash got %+v", credit)
+	}
+	if b := balance(t, s, from.ID); b != 100 {
+		t.Fatalf("expected balance 100 got %d", b)
+	}
+
+	// Other outputs of the on-chain transaction can be credited.
+	if _, err := s.InjectTransaction(service.MainNet, service.Transaction{
+		Type:        "credit",
+		ToAccountID: to.ID,
+		Value:       1,
+		TxHashes:    credit.TxHashes,
+		TxIndex:     1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(s, "GET",
+		fmt.Sprintf("/v1/mainnet/transactions/%d", tx.ID), nil)
+	expectCode(t, w, http.StatusOK)
+
+	for _, tx := range []service.Transaction{
+		{Type: "transfer", FromAccountID: from.ID, ToAccountID: 999,
+			Value: 1},
+		{Type: "debit", FromAccountID: from.ID, Value: 1},
+		{Type: "credit", ToAccountID: to.ID, Value: 0},
+		{Type: "swap", FromAccountID: from.ID, Value: 1},
+		{ID: tx.ID, Type: "credit", ToAccountID: to.ID, Value: 1},
+		{Type: "credit", ToAccountID: to.ID, Value: 1,
+			TxHashes: credit.TxHashes},
+		{Type: "credit", ToAccountID: to.ID, Value: 1,
+			TxHashes: credit.TxHashes, TxIndex: 1},
+	} {
+		if _, err := s.InjectTransaction(service.MainNet, tx); err == nil {
+			t.Fatalf("expected error injecting %+v", tx)
+		}
+	}
 }
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// violationHeader is added to responses, once for each way the request or
+// response does not match the OpenAPI document, if the Validate option is
+// set.
+const violationHeader = "X-Mock-Violation"
+
+// check returns the ways in which v, decoded from JSON with numbers kept as
+// json.Number, does not match schema. at is the location of v, used in the
+// descriptions returned.
+func (ss schemas) check(schema jsonObject, v interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		return ss.check(ss[name].(jsonObject), v, at)
+	}
+	if v == nil && schema["nullable"] == true {
+		return nil
+	}
+	if allOf, ok := schema["allOf"].([]jsonObject); ok {
+		violations := []string{}
+		for _, s := range allOf {
+			violations = append(violations, ss.check(s, v, at)...)
+		}
+		return violations
+	}
+	if oneOf, ok := schema["oneOf"].([]jsonObject); ok {
+		matched := 0
+		for _, s := range oneOf {
+			if len(ss.check(s, v, at)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{at + ": does not match exactly one schema"}
+		}
+		return nil
+	}
+
+	ty, _ := schema["type"].(string)
+	mismatch := []string{fmt.Sprintf("%s: expected %s", at, ty)}
+
+	switch ty {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		violations := []string{}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, exists := obj[name]; !exists {
+				violations = append(violations, at+"."+name+": required")
+			}
+		}
+
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		properties, _ := schema["properties"].(jsonObject)
+		additional, _ := schema["additionalProperties"].(jsonObject)
+		for _, name := range names {
+			s, declared := properties[name].(jsonObject)
+			if !declared {
+				s = additional
+			}
+			if s == nil {
+				violations = append(violations,
+					at+"."+name+": not declared")
+				continue
+			}
+			violations = append(violations,
+				ss.check(s, obj[name], at+"."+name)...)
+		}
+		return violations
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		violations := []string{}
+		items := schema["items"].(jsonObject)
+		for i, value := range arr {
+			violations = append(violations, ss.check(items, value,
+				at+"["+strconv.Itoa(i)+"]")...)
+		}
+		return violations
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return mismatch
+		}
+		if enum, ok := schema["enum"].([]string); ok {
+			for _, value := range enum {
+				if s == value {
+					return nil
+				}
+			}
+			return []string{fmt.Sprintf("%s: expected one of %s", at,
+				strings.Join(enum, ", "))}
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return mismatch
+		}
+		if _, err := n.Int64(); err != nil {
+			return mismatch
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return mismatch
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+// checkJSON returns the ways in which the JSON body does not match schema.
+func (ss schemas) checkJSON(schema jsonObject, body []byte,
+	at string) []string {
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return []string{at + ": invalid json"}
+	}
+	return ss.check(schema, v, at)
+}
+
+// contract is the part of the OpenAPI document describing a route.
+type contract struct {
+	schemas  schemas
+	route    route
+	request  jsonObject
+	response jsonObject
+	errors   jsonObject
+}
+
+func newContract(ss schemas, rt route) contract {
+	return contract{
+		schemas:  ss,
+		route:    rt,
+		request:  ss.requestSchema(rt),
+		response: ss.responseSchema(rt),
+		errors:   ss.errorsSchema(),
+	}
+}
+
+// checkRequest returns the ways in which the query and body of a request do
+// not match the contract.
+func (ct contract) checkRequest(r *http.Request, body []byte) []string {
+	violations := []string{}
+
+	query := r.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		declared := false
+		for _, q := range ct.route.query {
+			declared = declared || q == name
+		}
+		if !declared {
+			violations = append(violations, "query."+name+": not declared")
+			continue
+		}
+		if _, err := strconv.ParseInt(query.Get(name), 10, 64); err != nil {
+			violations = append(violations,
+				"query."+name+": expected integer")
+		}
+	}
+
+	empty := len(bytes.TrimSpace(body)) == 0
+	switch {
+	case ct.request == nil && !empty:
+		violations = append(violations, "body: not expected")
+	case ct.request != nil && empty:
+		violations = append(violations, "body: required")
+	case ct.request != nil:
+		violations = append(violations,
+			ct.schemas.checkJSON(ct.request, body, "body")...)
+	}
+	return violations
+}
+
+// checkResponse returns the ways in which a response does not match the
+// contract.
+func (ct contract) checkResponse(status int, body []byte) []string {
+	switch {
+	case status >= 400:
+		return ct.schemas.checkJSON(ct.errors, body, "response")
+	case status != ct.route.status:
+		return []string{fmt.Sprintf("response: status %d not documented",
+			status)}
+	}
+
+	switch {
+	case ct.response != nil:
+		return ct.schemas.checkJSON(ct.response, body, "response")
+	case len(bytes.TrimSpace(body)) > 0:
+		return []string{"response: body not expected"}
+	}
+	return nil
+}
+
+// responseBuffer holds a response until it has been validated.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// validated returns routes with handlers that, if the Validate option is set,
+// check requests and responses against the OpenAPI document. Violations are
+// reported in X-Mock-Violation headers and the journal. In strict mode
+// requests that violate the document are rejected and responses that do are
+// replaced with an error.
+func (c *chain) validated(routes []route) []route {
+	if !c.validate {
+		return routes
+	}
+
+	// The schemas are all generated here so that they are only read while
+	// serving requests.
+	ss := schemas{}
+	validated := make([]route, len(routes))
+	for i, rt := range routes {
+		rt.handler = c.validateHandler(newContract(ss, rt), rt.handler)
+		validated[i] = rt
+	}
+	return validated
+}
+
+func (c *chain) validateHandler(ct contract,
+	next http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		violations := ct.checkRequest(r, body)
+		if len(violations) > 0 && c.strict {
+			reportViolations(w, r, violations)
+			sendError(w, http.StatusBadRequest, errorf("contract_violation",
+				"request does not match the OpenAPI document: %s",
+				strings.Join(violations, "; ")))
+			return
+		}
+
+		buf := &responseBuffer{header: w.Header()}
+		next(buf, r)
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		responseViolations := ct.checkResponse(buf.status, buf.body.Bytes())
+		violations = append(violations, responseViolations...)
+		reportViolations(w, r, violations)
+
+		if len(responseViolations) > 0 && c.strict {
+			sendError(w, http.StatusInternalServerError, errorf(
+				"contract_violation",
+				"response does not match the OpenAPI document: %s",
+				strings.Join(responseViolations, "; ")))
+			return
+		}
+
+		w.WriteHeader(buf.status)
+		w.Write(buf.body.Bytes())
+	}
+}
+
+// reportViolations adds violations to the headers of the response and to the
+// journal entry of r.
+func reportViolations(w http.ResponseWriter, r *http.Request,
+	violations []string) {
+
+	for _, v := range violations {
+		w.Header().Add(violationHeader, v)
+	}
+	if e, ok := r.Context().Value(journalEntryKey{}).(*JournalEntry); ok {
+		e.Violations = append(e.Violations, violations...)
+	}
+}
+
+// Validate is an option that can be passed to New() to check every request to
+// the specified network, and the response sent, against the OpenAPI document
+// served at /_mock/[network]/openapi.json. Violations are reported in
+// X-Mock-Violation response headers and in the journal. If the Strict option
+// is also set requests that violate the document are rejected and responses
+// that do are replaced with a 500 Internal Server Error.
+func Validate(network Network) Option {
+	return func(c *chain) {
+		if c.network == network {
+			c.validate = true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rtwire/mock/service"
+)
+
+func expectViolations(t *testing.T, w *httptest.ResponseRecorder,
+	violations ...string) {
+
+	got := w.Header()["X-Mock-Violation"]
+	if len(got) == 0 && len(violations) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, violations) {
+		t.Fatalf("expected violations %v got %v", violations, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := service.New(service.Validate(service.MainNet))
+
+	fromAccID := fundedAccount(t, s, 1000)
+	toAccID := createAccount(t, s)
+
+	w := serve(s, "PUT", "/v1/mainnet/transactions/",
+		transferPayload{createTxID(t, s), fromAccID, toAccID, 100})
+	expectCode(t, w, http.StatusCreated)
+	expectViolations(t, w)
+
+	url := fmt.Sprintf("/v1/mainnet/accounts/%d/transactions/?limit=10",
+		fromAccID)
+	w = serve(s, "GET", url, nil)
+	expectCode(t, w, http.StatusOK)
+	expectViolations(t, w)
+
+	w = serveAdmin(s, "POST", "/_mock/mainnet/blocks/",
+		map[string]int{"n": 1})
+	expectCode(t, w, http.StatusCreated)
+	expectViolations(t, w)
+
+	w = serveAdmin(s, "GET", "/_mock/mainnet/state/", nil)
+	expectCode(t, w, http.StatusOK)
+	expectViolations(t, w)
+
+	// Violations are reported without changing the response.
+	w = serve(s, "POST", "/v1/mainnet/transactions/",
+		map[string]interface{}{"n": 1, "memo": "x"})
+	expectCode(t, w, http.StatusCreated)
+	expectViolations(t, w, "body.memo: not declared")
+
+	w = serve(s, "GET", "/v1/mainnet/accounts/?limit=x&page=2", nil)
+	expectCode(t, w, http.StatusBadRequest)
+	expectViolations(t, w, "query.limit: expected integer",
+		"query.page: not declared")
+
+	journal := s.Journal(service.MainNet)
+	e := journal[len(journal)-1]
+	if !reflect.DeepEqual(e.Violations, []string{
+		"query.limit: expected integer",
+		"query.page: not declared",
+	}) {
+		t.Fatalf("unexpected journal violations %v", e.Violations)
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	s := service.New(
+		service.Validate(service.MainNet),
+		service.Strict(service.MainNet))
+
+	w := serve(s, "GET", "/v1/mainnet/fees/?page=1", nil)
+	expectViolations(t, w, "query.page: not declared")
+	expectErrorCode(t, w, http.StatusBadRequest, "contract_violation")
+
+	w = serve(s, "GET", "/v1/mainnet/fees/", nil)
+	expectCode(t, w, http.StatusOK)
+	expectViolations(t, w)
+}